@@ -0,0 +1,169 @@
+package backoff
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAdaptiveMultiplier = 2.0
+)
+
+// AdaptivePolicy is a policy whose interval adapts to the outcome of the
+// attempts, using additive-increase/multiplicative-decrease (AIMD) on
+// the rate of attempts: every failure multiplies the interval by the
+// multiplier, and every success decreases it by a fixed step, within
+// the minimum and maximum intervals.
+//
+// The interval is shared by all the controllers started from the same
+// policy, so a single AdaptivePolicy can be used by all the callers of
+// a dependency: when errors rise, every caller slows down, and as they
+// clear, every caller speeds up again.
+//
+// Outcomes are reported via the Success and Failure methods of either the
+// policy or the controllers (see FeedbackController). When reported via
+// a controller, the controller also reschedules its next event using the
+// updated interval.
+type AdaptivePolicy struct {
+	cOptions    []ControllerOption
+	current     float64
+	jitter      jitter
+	maxInterval float64
+	minInterval float64
+	multiplier  float64
+	mu          *sync.Mutex
+	step        float64
+}
+
+// NewAdaptivePolicy creates a new AdaptivePolicy. The interval starts at
+// the minimum interval (WithMinInterval, 500ms by default), and is
+// bounded by WithMaxInterval (1 minute by default). Each failure
+// multiplies the interval by WithMultiplier (2.0 by default), and each
+// success decreases it by WithAdditiveStep (which defaults to the
+// minimum interval).
+//
+// As with the other policies, the controllers try up to 10 times unless
+// WithMaxRetries is specified.
+func NewAdaptivePolicy(options ...ExponentialOption) *AdaptivePolicy {
+	var cOptions []ControllerOption
+	jitterFactor := 0.0
+	maxInterval := defaultMaxInterval
+	minInterval := defaultMinInterval
+	multiplier := defaultAdaptiveMultiplier
+	var rng Random
+	var jitterStrategy JitterStrategy
+	step := -1.0
+
+	for _, option := range options {
+		if opt, ok := option.(ControllerOption); ok {
+			cOptions = append(cOptions, opt)
+			continue
+		}
+
+		// The rest are the options for the intervals
+		switch option.Ident() {
+		case identAdditiveStep{}:
+			step = float64(option.Value().(time.Duration))
+		case identJitterFactor{}:
+			jitterFactor = option.Value().(float64)
+		case identJitterStrategy{}:
+			jitterStrategy = option.Value().(JitterStrategy)
+		case identMaxInterval{}:
+			maxInterval = float64(option.Value().(time.Duration))
+		case identMinInterval{}:
+			minInterval = float64(option.Value().(time.Duration))
+		case identMultiplier{}:
+			multiplier = option.Value().(float64)
+		case identRNG{}:
+			rng = option.Value().(Random)
+		}
+	}
+
+	if minInterval > maxInterval {
+		minInterval = maxInterval
+	}
+	if multiplier <= 1 {
+		multiplier = defaultAdaptiveMultiplier
+	}
+	if step < 0 {
+		step = minInterval
+	}
+
+	return &AdaptivePolicy{
+		cOptions:    cOptions,
+		current:     minInterval,
+		jitter:      newJitter(jitterFactor, rng, jitterStrategy),
+		maxInterval: maxInterval,
+		minInterval: minInterval,
+		multiplier:  multiplier,
+		mu:          &sync.Mutex{},
+		step:        step,
+	}
+}
+
+// Interval returns the current interval, without jitter.
+func (p *AdaptivePolicy) Interval() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Duration(p.current)
+}
+
+// Success decreases the interval by the additive step.
+func (p *AdaptivePolicy) Success() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current -= p.step
+	if p.current < p.minInterval {
+		p.current = p.minInterval
+	}
+}
+
+// Failure multiplies the interval by the multiplier.
+func (p *AdaptivePolicy) Failure() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current *= p.multiplier
+	if p.current > p.maxInterval {
+		p.current = p.maxInterval
+	}
+	if p.current < p.minInterval {
+		p.current = p.minInterval
+	}
+}
+
+func (p *AdaptivePolicy) next() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Duration(p.jitter.apply(p.current))
+}
+
+func (p *AdaptivePolicy) Start(ctx context.Context) Controller {
+	return newController(ctx, &adaptiveInterval{policy: p}, p.cOptions...)
+}
+
+// adaptiveInterval is the interval generator used by the controllers of
+// AdaptivePolicy. All of them share the state of the policy
+type adaptiveInterval struct {
+	policy *AdaptivePolicy
+}
+
+func (g *adaptiveInterval) Next() time.Duration {
+	return g.policy.next()
+}
+
+func (g *adaptiveInterval) Feedback(f Feedback) {
+	if f.Success {
+		g.policy.Success()
+		return
+	}
+	g.policy.Failure()
+}
+
+func (g *adaptiveInterval) String() string {
+	p := g.policy
+	return fmt.Sprintf("adaptive(min=%s, max=%s, multiplier=%g, step=%s)", time.Duration(p.minInterval), time.Duration(p.maxInterval), p.multiplier, time.Duration(p.step))
+}
@@ -0,0 +1,135 @@
+package backoff_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+type feedbackWaitRecorder struct {
+	backoff.NopObserver
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (r *feedbackWaitRecorder) OnWait(_ int, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waits = append(r.waits, d)
+}
+
+func (r *feedbackWaitRecorder) last() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.waits) == 0 {
+		return 0
+	}
+	return r.waits[len(r.waits)-1]
+}
+
+func TestAdaptivePolicy(t *testing.T) {
+	t.Run("AIMD", func(t *testing.T) {
+		p := backoff.NewAdaptivePolicy(
+			backoff.WithMinInterval(100*time.Millisecond),
+			backoff.WithMaxInterval(time.Second),
+			backoff.WithMultiplier(2),
+			backoff.WithAdditiveStep(50*time.Millisecond),
+		)
+
+		if !assert.Equal(t, 100*time.Millisecond, p.Interval(), `interval should start at the minimum`) {
+			return
+		}
+
+		for _, expected := range []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+			p.Failure()
+			if !assert.Equal(t, expected, p.Interval(), `failure should multiply the interval`) {
+				return
+			}
+		}
+
+		for _, expected := range []time.Duration{950 * time.Millisecond, 900 * time.Millisecond} {
+			p.Success()
+			if !assert.Equal(t, expected, p.Interval(), `success should decrease the interval`) {
+				return
+			}
+		}
+
+		for i := 0; i < 100; i++ {
+			p.Success()
+		}
+		if !assert.Equal(t, 100*time.Millisecond, p.Interval(), `interval should not go below the minimum`) {
+			return
+		}
+	})
+	t.Run("Controller feedback", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		clock := backofftest.NewFakeClock(time.Time{})
+		r := &feedbackWaitRecorder{}
+		p := backoff.NewAdaptivePolicy(
+			backoff.WithMinInterval(time.Second),
+			backoff.WithMaxInterval(time.Minute),
+			backoff.WithClock(clock),
+			backoff.WithObserver(r),
+		)
+
+		// another caller of the same dependency
+		other := p.Start(ctx)
+
+		c := p.Start(ctx)
+		if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+			return
+		}
+		backoff.Failure(c, nil)
+
+		// the next event is rescheduled using the new interval
+		if !assert.Eventually(t, func() bool {
+			return r.last() == 2*time.Second
+		}, time.Second, time.Millisecond, `wait should be rescheduled`) {
+			return
+		}
+		clock.BlockUntil(2)
+		clock.Advance(time.Second)
+		select {
+		case <-c.Next():
+			t.Errorf(`event should not fire after the old interval`)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+		clock.Advance(time.Second)
+		if !assert.True(t, backoff.Continue(c), `event should fire after the new interval`) {
+			return
+		}
+
+		// the other controller shares the interval
+		if !assert.True(t, backoff.Continue(other), `first attempt should be allowed`) {
+			return
+		}
+		if !assert.Equal(t, 2*time.Second, p.Interval(), `interval should be shared`) {
+			return
+		}
+		backoff.Success(other)
+		if !assert.Equal(t, time.Second, p.Interval(), `success from any controller should decrease the interval`) {
+			return
+		}
+	})
+	t.Run("Feedback is ignored by other policies", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		for _, p := range []backoff.Policy{backoff.Null(), backoff.Constant(), backoff.Exponential()} {
+			c, ok := p.Start(ctx).(backoff.FeedbackController)
+			if !assert.True(t, ok, `%T should start a FeedbackController`, p) {
+				return
+			}
+			c.Success()
+			c.Failure(nil)
+		}
+	})
+}
@@ -0,0 +1,12 @@
+//go:build go1.21
+
+package backoff
+
+import "context"
+
+// afterFunc calls f in its own goroutine once ctx is done. Calling the
+// returned function stops the association. See context.AfterFunc
+func afterFunc(ctx context.Context, f func()) func() {
+	stop := context.AfterFunc(ctx, f)
+	return func() { stop() }
+}
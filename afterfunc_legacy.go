@@ -0,0 +1,28 @@
+//go:build !go1.21
+
+package backoff
+
+import (
+	"context"
+	"sync"
+)
+
+// afterFunc calls f in its own goroutine once ctx is done. Calling the
+// returned function stops the association. context.AfterFunc is not
+// available before Go 1.21, so a goroutine waits for ctx instead
+func afterFunc(ctx context.Context, f func()) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	go func() {
+		select {
+		case <-ctx.Done():
+			f()
+		case <-stop:
+		}
+	}()
+	return func() { once.Do(func() { close(stop) }) }
+}
@@ -1,8 +1,8 @@
 package backoff
 
 // Null creates a new NullPolicy object
-func Null() Policy {
-	return NewNull()
+func Null(options ...ControllerOption) Policy {
+	return NewNull(options...)
 }
 
 // Constant creates a new ConstantPolicy object
@@ -15,6 +15,11 @@ func Exponential(options ...ExponentialOption) Policy {
 	return NewExponentialPolicy(options...)
 }
 
+// GRPCConnect creates a new GRPCConnectPolicy object
+func GRPCConnect(options ...ExponentialOption) Policy {
+	return NewGRPCConnectPolicy(options...)
+}
+
 // Continue is a convenience function to check when we can fire
 // the next invocation of the desired backoff code
 //
@@ -29,3 +34,21 @@ func Continue(c Controller) bool {
 		return ok
 	}
 }
+
+// Success reports that the latest attempt has succeeded, if the
+// controller implements FeedbackController. Otherwise it does nothing,
+// so it is safe to be used with any Controller.
+func Success(c Controller) {
+	if fc, ok := c.(FeedbackController); ok {
+		fc.Success()
+	}
+}
+
+// Failure reports that the latest attempt has failed with `err`, which
+// may be nil, if the controller implements FeedbackController. Otherwise
+// it does nothing, so it is safe to be used with any Controller.
+func Failure(c Controller, err error) {
+	if fc, ok := c.(FeedbackController); ok {
+		fc.Failure(err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -218,3 +219,33 @@ func TestConstantWithJitter(t *testing.T) {
 		return
 	}
 }
+
+func TestFeedback(t *testing.T) {
+	t.Run("FeedbackController", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		p := backoff.NewAdaptivePolicy(backoff.WithMinInterval(time.Second))
+		c := p.Start(ctx)
+		if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+			return
+		}
+
+		backoff.Failure(c, nil)
+		if !assert.Equal(t, 2*time.Second, p.Interval(), `failure should be reported`) {
+			return
+		}
+		backoff.Success(c)
+		if !assert.Equal(t, time.Second, p.Interval(), `success should be reported`) {
+			return
+		}
+	})
+	t.Run("Other controllers", func(t *testing.T) {
+		c := backofftest.NewScriptedController()
+		defer c.Stop()
+
+		// must not panic
+		backoff.Failure(c, nil)
+		backoff.Success(c)
+	})
+}
@@ -0,0 +1,41 @@
+// Package backoffaws exposes backoff policies through the BackoffDelayer
+// interface of the AWS SDK for Go v2 (github.com/aws/aws-sdk-go-v2/aws/retry),
+// so that the SDK's retries follow the same intervals as the rest of
+// the application.
+//
+// The SDK is not imported: Delayer satisfies retry.BackoffDelayer
+// structurally, and can be used as follows:
+//
+//	retryer := retry.NewStandard(func(o *retry.StandardOptions) {
+//		o.Backoff = backoffaws.NewDelayer(backoff.NewExponentialPolicy(...))
+//	})
+package backoffaws
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// Delayer computes the delay before a retry from an
+// AttemptIntervalGenerator, such as ExponentialPolicy or ConstantPolicy.
+//
+// Note that the number of attempts is controlled by the SDK (see
+// retry.StandardOptions.MaxAttempts), not by the policy: options such as
+// backoff.WithMaxRetries have no effect here.
+type Delayer struct {
+	g backoff.AttemptIntervalGenerator
+}
+
+// NewDelayer creates a new Delayer. The AttemptIntervalGenerator must be
+// safe for concurrent use, as the SDK may compute delays for multiple
+// requests at once. The policies in the backoff package are.
+func NewDelayer(g backoff.AttemptIntervalGenerator) *Delayer {
+	return &Delayer{g: g}
+}
+
+// BackoffDelay returns the delay before retrying after the given
+// (1-based) attempt has failed. The error is not used.
+func (d *Delayer) BackoffDelay(attempt int, _ error) (time.Duration, error) {
+	return d.g.IntervalFor(attempt), nil
+}
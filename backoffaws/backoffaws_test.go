@@ -0,0 +1,84 @@
+package backoffaws_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffaws"
+	"github.com/stretchr/testify/assert"
+)
+
+// backoffDelayer is the same as retry.BackoffDelayer in the AWS SDK
+type backoffDelayer interface {
+	BackoffDelay(attempt int, err error) (time.Duration, error)
+}
+
+var _ backoffDelayer = (*backoffaws.Delayer)(nil)
+
+func TestDelayer(t *testing.T) {
+	t.Run("Exponential", func(t *testing.T) {
+		p := backoff.NewExponentialPolicy(
+			backoff.WithMinInterval(time.Second),
+			backoff.WithMultiplier(2),
+			backoff.WithMaxInterval(10*time.Second),
+		)
+		d := backoffaws.NewDelayer(p)
+
+		// must match the stateful interval generator
+		ig := backoff.NewExponentialInterval(
+			backoff.WithMinInterval(time.Second),
+			backoff.WithMultiplier(2),
+			backoff.WithMaxInterval(10*time.Second),
+		)
+		for attempt := 1; attempt <= 6; attempt++ {
+			delay, err := d.BackoffDelay(attempt, errors.New(`dummy`))
+			if !assert.NoError(t, err, `BackoffDelay should succeed`) {
+				return
+			}
+			if !assert.Equal(t, ig.Next(), delay, `delay for attempt %d`, attempt) {
+				return
+			}
+		}
+
+		// attempts are independent of each other
+		delay, _ := d.BackoffDelay(2, nil)
+		if !assert.Equal(t, 2*time.Second, delay, `delay for attempt 2`) {
+			return
+		}
+	})
+	t.Run("Constant", func(t *testing.T) {
+		d := backoffaws.NewDelayer(backoff.NewConstantPolicy(backoff.WithInterval(3 * time.Second)))
+		for _, attempt := range []int{1, 5, 100} {
+			delay, _ := d.BackoffDelay(attempt, nil)
+			if !assert.Equal(t, 3*time.Second, delay, `delay for attempt %d`, attempt) {
+				return
+			}
+		}
+	})
+	t.Run("Concurrent jitter", func(t *testing.T) {
+		d := backoffaws.NewDelayer(backoff.NewExponentialPolicy(
+			backoff.WithMinInterval(time.Second),
+			backoff.WithJitterFactor(0.5),
+		))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for attempt := 1; attempt < 100; attempt++ {
+					_, _ = d.BackoffDelay(attempt, nil)
+				}
+			}()
+		}
+		wg.Wait()
+
+		delay, _ := d.BackoffDelay(1, nil)
+		if !assert.True(t, delay >= 500*time.Millisecond && delay <= 1500*time.Millisecond, `jittered delay (%s)`, delay) {
+			return
+		}
+	})
+}
@@ -0,0 +1,158 @@
+// Package backoffcenkalti provides adapters between this library and
+// github.com/cenkalti/backoff, so that code using either library can be
+// migrated gradually.
+package backoffcenkalti
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cenkalti "github.com/cenkalti/backoff"
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// intervalBackOff exposes an IntervalGenerator as a cenkalti.BackOff
+type intervalBackOff struct {
+	ig         backoff.IntervalGenerator
+	maxRetries int
+	mu         *sync.Mutex
+	newIG      func() backoff.IntervalGenerator
+	retries    int
+}
+
+// NewIntervalBackOff creates a cenkalti.BackOff that returns the intervals
+// generated by an IntervalGenerator. `newIG` is called to create a fresh
+// generator every time the BackOff is reset.
+//
+// After `maxRetries` intervals have been returned, cenkalti.Stop is
+// returned. If `maxRetries` is 0, the BackOff never stops (unless the
+// generator returns a negative interval)
+func NewIntervalBackOff(newIG func() backoff.IntervalGenerator, maxRetries int) cenkalti.BackOff {
+	return &intervalBackOff{
+		ig:         newIG(),
+		maxRetries: maxRetries,
+		mu:         &sync.Mutex{},
+		newIG:      newIG,
+	}
+}
+
+func (b *intervalBackOff) NextBackOff() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxRetries > 0 && b.retries >= b.maxRetries {
+		return cenkalti.Stop
+	}
+	b.retries++
+
+	d := b.ig.Next()
+	if d < 0 {
+		return cenkalti.Stop
+	}
+	return d
+}
+
+func (b *intervalBackOff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ig = b.newIG()
+	b.retries = 0
+}
+
+// policyBackOff exposes a Policy as a cenkalti.BackOff
+type policyBackOff struct {
+	cancel     func()
+	controller backoff.Controller
+	ctx        context.Context
+	mu         *sync.Mutex
+	policy     backoff.Policy
+}
+
+// NewBackOff creates a cenkalti.BackOff from any Policy. A controller is
+// started from the policy when the BackOff is created, and every time
+// it is reset. Controllers are stopped when `ctx` is done.
+//
+// Because controllers fire their events by themselves, NextBackOff blocks
+// until the controller fires its next event, and then returns 0. When
+// the controller is done, cenkalti.Stop is returned. This means that
+// functions such as cenkalti.Retry work as expected, but the returned
+// durations can not be used to find out what the intervals are. Use
+// NewIntervalBackOff for that.
+func NewBackOff(ctx context.Context, policy backoff.Policy) cenkalti.BackOff {
+	b := &policyBackOff{
+		ctx:    ctx,
+		mu:     &sync.Mutex{},
+		policy: policy,
+	}
+	b.start()
+	return b
+}
+
+// start creates a new controller, and consumes the first event, which
+// corresponds to the initial call that cenkalti.BackOff users make
+// before consulting NextBackOff. Must be called with the lock held
+func (b *policyBackOff) start() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+
+	cctx, cancel := context.WithCancel(b.ctx)
+	b.cancel = cancel
+	b.controller = b.policy.Start(cctx)
+	backoff.Continue(b.controller)
+}
+
+func (b *policyBackOff) NextBackOff() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !backoff.Continue(b.controller) {
+		return cenkalti.Stop
+	}
+	return 0
+}
+
+func (b *policyBackOff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.start()
+}
+
+// backOffInterval exposes a cenkalti.BackOff as an IntervalGenerator
+type backOffInterval struct {
+	b cenkalti.BackOff
+}
+
+func (g *backOffInterval) Next() time.Duration {
+	// cenkalti.Stop is negative, which tells the controller to stop
+	return g.b.NextBackOff()
+}
+
+// Policy is a backoff.Policy that uses a cenkalti.BackOff to compute
+// the intervals between events
+type Policy struct {
+	cOptions   []backoff.ControllerOption
+	newBackOff func() cenkalti.BackOff
+}
+
+// NewPolicy creates a backoff.Policy whose controllers fire events at the
+// intervals returned by a cenkalti.BackOff. `newBackOff` is called every
+// time a controller is started, because cenkalti.BackOff objects are
+// stateful and can not be shared among controllers.
+//
+// The controller is done once the BackOff returns cenkalti.Stop. Unlike
+// other policies, there is no limit on the number of retries unless
+// backoff.WithMaxRetries is specified.
+func NewPolicy(newBackOff func() cenkalti.BackOff, options ...backoff.ControllerOption) *Policy {
+	return &Policy{
+		cOptions:   append([]backoff.ControllerOption{backoff.WithMaxRetries(0)}, options...),
+		newBackOff: newBackOff,
+	}
+}
+
+func (p *Policy) Start(ctx context.Context) backoff.Controller {
+	b := p.newBackOff()
+	b.Reset()
+	return backoff.NewController(ctx, &backOffInterval{b: b}, p.cOptions...)
+}
@@ -0,0 +1,127 @@
+package backoffcenkalti_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cenkalti "github.com/cenkalti/backoff"
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffcenkalti"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalBackOff(t *testing.T) {
+	b := backoffcenkalti.NewIntervalBackOff(func() backoff.IntervalGenerator {
+		return backoff.NewExponentialInterval(
+			backoff.WithMinInterval(time.Second),
+			backoff.WithMultiplier(2),
+		)
+	}, 3)
+
+	for i := 0; i < 2; i++ {
+		expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, cenkalti.Stop}
+		for j, d := range expected {
+			if !assert.Equal(t, d, b.NextBackOff(), `interval %d (round %d)`, j, i) {
+				return
+			}
+		}
+		b.Reset()
+	}
+}
+
+func TestBackOff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := backoffcenkalti.NewBackOff(ctx, backoff.Constant(
+		backoff.WithInterval(10*time.Millisecond),
+		backoff.WithMaxRetries(3),
+	))
+
+	var calls int
+	err := cenkalti.Retry(func() error {
+		calls++
+		return errors.New(`dummy`)
+	}, b)
+	if !assert.Error(t, err, `cenkalti.Retry should fail`) {
+		return
+	}
+	if !assert.Equal(t, 4, calls, `initial + 3 retries`) {
+		return
+	}
+
+	// Reset starts a new controller
+	calls = 0
+	err = cenkalti.Retry(func() error {
+		calls++
+		if calls < 3 {
+			return errors.New(`dummy`)
+		}
+		return nil
+	}, b)
+	if !assert.NoError(t, err, `cenkalti.Retry should succeed`) {
+		return
+	}
+	if !assert.Equal(t, 3, calls, `should succeed on the third call`) {
+		return
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	t.Run("Stops when the BackOff stops", func(t *testing.T) {
+		p := backoffcenkalti.NewPolicy(func() cenkalti.BackOff {
+			return cenkalti.WithMaxRetries(cenkalti.NewConstantBackOff(10*time.Millisecond), 3)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var count int
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+			count++
+		}
+		if !assert.Equal(t, 4, count, `initial + 3 retries`) {
+			return
+		}
+		if !assert.NoError(t, ctx.Err(), `should stop before the context is done`) {
+			return
+		}
+	})
+	t.Run("Immediate stop", func(t *testing.T) {
+		p := backoffcenkalti.NewPolicy(func() cenkalti.BackOff {
+			return &cenkalti.StopBackOff{}
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var count int
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+			count++
+		}
+		if !assert.Equal(t, 1, count, `should fire exactly once`) {
+			return
+		}
+	})
+	t.Run("WithMaxRetries", func(t *testing.T) {
+		p := backoffcenkalti.NewPolicy(func() cenkalti.BackOff {
+			return &cenkalti.ZeroBackOff{}
+		}, backoff.WithMaxRetries(2))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var count int
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+			count++
+		}
+		if !assert.Equal(t, 3, count, `initial + 2 retries`) {
+			return
+		}
+	})
+}
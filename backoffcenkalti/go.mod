@@ -0,0 +1,11 @@
+module github.com/lestrrat-go/backoff/v2/backoffcenkalti
+
+go 1.16
+
+require (
+	github.com/cenkalti/backoff v2.2.1+incompatible
+	github.com/lestrrat-go/backoff/v2 v2.1.0
+	github.com/stretchr/testify v1.6.1
+)
+
+replace github.com/lestrrat-go/backoff/v2 => ../
@@ -0,0 +1,256 @@
+// Package backoffconfig describes backoff policies in configuration
+// files, so that the policy used by a service can be changed without
+// modifying its code. The policy is described by a JSON object, for
+// example:
+//
+//	{"type": "exponential", "min_interval": "500ms", "max_interval": "1m", "multiplier": 1.5, "jitter_factor": 0.05, "max_retries": 10}
+//	{"type": "constant", "interval": "1s", "max_retries": 5}
+//	{"type": "null"}
+//
+// and can be read with Parse:
+//
+//	cfg, err := backoffconfig.Parse(f)
+//	if err != nil {
+//		return err
+//	}
+//	policy := cfg.Policy()
+//
+// Config may also be embedded in a larger configuration structure, in
+// which case Validate should be called after it has been unmarshaled.
+//
+// This is the same format as the one used by cmd/backoffsim, so the
+// schedule of a configured policy can be inspected before it is deployed.
+package backoffconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// Duration is a time.Duration that is represented as a string such as
+// "500ms" or "1m30s" in the configuration file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf(`expected a duration string such as "500ms": %w`, err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Config describes a backoff policy. The field names mirror the option
+// names accepted by the policy constructors (WithInterval, WithMinInterval,
+// WithMaxInterval, WithMultiplier, WithJitterFactor, WithMaxRetries).
+// Fields that are not specified take the defaults of the constructors.
+type Config struct {
+	Type         string    `json:"type"`
+	Interval     *Duration `json:"interval,omitempty"`
+	MinInterval  *Duration `json:"min_interval,omitempty"`
+	MaxInterval  *Duration `json:"max_interval,omitempty"`
+	Multiplier   *float64  `json:"multiplier,omitempty"`
+	JitterFactor *float64  `json:"jitter_factor,omitempty"`
+	MaxRetries   *int      `json:"max_retries,omitempty"`
+}
+
+// Policy types accepted in the "type" field
+const (
+	TypeNull        = "null"
+	TypeConstant    = "constant"
+	TypeExponential = "exponential"
+)
+
+// defaultMaxRetries is the number of retries controllers perform
+// unless WithMaxRetries is specified
+const defaultMaxRetries = 10
+
+// Parse reads a single policy object from `src`, and validates it.
+// Unknown fields are rejected.
+func Parse(src io.Reader) (*Config, error) {
+	buf, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to read configuration: %w`, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf(`failed to parse configuration: %w`, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf(`failed to parse configuration: trailing data after policy object`)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf(`invalid configuration: %w`, err)
+	}
+	return &cfg, nil
+}
+
+// Validate rejects values that the policy constructors would otherwise
+// silently ignore or replace with defaults, so that a typo in a
+// configuration file does not go unnoticed. The type is normalized to
+// lower case.
+func (cfg *Config) Validate() error {
+	cfg.Type = strings.ToLower(cfg.Type)
+
+	var allowed map[string]bool
+	switch cfg.Type {
+	case TypeNull:
+		allowed = map[string]bool{}
+	case TypeConstant:
+		allowed = map[string]bool{"interval": true, "jitter_factor": true, "max_retries": true}
+	case TypeExponential:
+		allowed = map[string]bool{"min_interval": true, "max_interval": true, "multiplier": true, "jitter_factor": true, "max_retries": true}
+	case "":
+		return fmt.Errorf(`"type" is required`)
+	default:
+		return fmt.Errorf(`unknown policy type %q`, cfg.Type)
+	}
+
+	for name, set := range map[string]bool{
+		"interval":      cfg.Interval != nil,
+		"min_interval":  cfg.MinInterval != nil,
+		"max_interval":  cfg.MaxInterval != nil,
+		"multiplier":    cfg.Multiplier != nil,
+		"jitter_factor": cfg.JitterFactor != nil,
+		"max_retries":   cfg.MaxRetries != nil,
+	} {
+		if set && !allowed[name] {
+			return fmt.Errorf(`%q is not valid for %s policies`, name, cfg.Type)
+		}
+	}
+
+	for name, d := range map[string]*Duration{
+		"interval":     cfg.Interval,
+		"min_interval": cfg.MinInterval,
+		"max_interval": cfg.MaxInterval,
+	} {
+		if d != nil && *d <= 0 {
+			return fmt.Errorf(`%q must be positive`, name)
+		}
+	}
+
+	if cfg.MinInterval != nil && cfg.MaxInterval != nil && *cfg.MinInterval > *cfg.MaxInterval {
+		return fmt.Errorf(`"min_interval" must not be greater than "max_interval"`)
+	}
+	if cfg.Multiplier != nil && *cfg.Multiplier <= 1 {
+		return fmt.Errorf(`"multiplier" must be greater than 1.0`)
+	}
+	if cfg.JitterFactor != nil && (*cfg.JitterFactor < 0 || *cfg.JitterFactor >= 1) {
+		return fmt.Errorf(`"jitter_factor" must be in the range 0.0 <= v < 1.0`)
+	}
+	if cfg.MaxRetries != nil && *cfg.MaxRetries < 0 {
+		return fmt.Errorf(`"max_retries" must not be negative`)
+	}
+	return nil
+}
+
+// Retries returns the number of retries the controllers of the policy
+// perform, using the same default as the controllers themselves. 0 means
+// "forever", and -1 means that the policy never retries.
+func (cfg *Config) Retries() int {
+	switch {
+	case cfg.Type == TypeNull:
+		return -1
+	case cfg.MaxRetries != nil:
+		return *cfg.MaxRetries
+	default:
+		return defaultMaxRetries
+	}
+}
+
+// Policy creates the configured policy. The options are passed to the
+// policy constructor along with those from the configuration, so that
+// observers, clocks and the like can be attached. The configuration
+// must have been validated.
+func (cfg *Config) Policy(options ...backoff.ControllerOption) backoff.Policy {
+	switch cfg.Type {
+	case TypeConstant:
+		var cOptions []backoff.Option
+		for _, option := range cfg.constantOptions(nil) {
+			cOptions = append(cOptions, option)
+		}
+		if cfg.MaxRetries != nil {
+			cOptions = append(cOptions, backoff.WithMaxRetries(*cfg.MaxRetries))
+		}
+		for _, option := range options {
+			cOptions = append(cOptions, option)
+		}
+		return backoff.Constant(cOptions...)
+	case TypeExponential:
+		eOptions := cfg.exponentialOptions(nil)
+		if cfg.MaxRetries != nil {
+			eOptions = append(eOptions, backoff.WithMaxRetries(*cfg.MaxRetries))
+		}
+		for _, option := range options {
+			eOptions = append(eOptions, option)
+		}
+		return backoff.Exponential(eOptions...)
+	}
+	return backoff.Null(options...)
+}
+
+// IntervalGenerator creates the interval generator that the controllers
+// of the configured policy use. If `rng` is not nil, it is used for
+// jittering. The Null policy has no intervals, so nil is returned.
+func (cfg *Config) IntervalGenerator(rng backoff.Random) backoff.IntervalGenerator {
+	switch cfg.Type {
+	case TypeConstant:
+		return backoff.NewConstantInterval(cfg.constantOptions(rng)...)
+	case TypeExponential:
+		return backoff.NewExponentialInterval(cfg.exponentialOptions(rng)...)
+	}
+	return nil
+}
+
+func (cfg *Config) constantOptions(rng backoff.Random) []backoff.ConstantOption {
+	var options []backoff.ConstantOption
+	if cfg.Interval != nil {
+		options = append(options, backoff.WithInterval(time.Duration(*cfg.Interval)))
+	}
+	if cfg.JitterFactor != nil {
+		options = append(options, backoff.WithJitterFactor(*cfg.JitterFactor))
+	}
+	if rng != nil {
+		options = append(options, backoff.WithRNG(rng))
+	}
+	return options
+}
+
+func (cfg *Config) exponentialOptions(rng backoff.Random) []backoff.ExponentialOption {
+	var options []backoff.ExponentialOption
+	if cfg.MinInterval != nil {
+		options = append(options, backoff.WithMinInterval(time.Duration(*cfg.MinInterval)))
+	}
+	if cfg.MaxInterval != nil {
+		options = append(options, backoff.WithMaxInterval(time.Duration(*cfg.MaxInterval)))
+	}
+	if cfg.Multiplier != nil {
+		options = append(options, backoff.WithMultiplier(*cfg.Multiplier))
+	}
+	if cfg.JitterFactor != nil {
+		options = append(options, backoff.WithJitterFactor(*cfg.JitterFactor))
+	}
+	if rng != nil {
+		options = append(options, backoff.WithRNG(rng))
+	}
+	return options
+}
@@ -0,0 +1,125 @@
+package backoffconfig_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cfg, err := backoffconfig.Parse(strings.NewReader(`{"type": "Exponential", "min_interval": "1s", "multiplier": 2, "max_retries": 3}`))
+	if !assert.NoError(t, err, `Parse should succeed`) {
+		return
+	}
+	if !assert.Equal(t, backoffconfig.TypeExponential, cfg.Type, `type should be normalized`) {
+		return
+	}
+	if !assert.Equal(t, 3, cfg.Retries(), `max retries should match`) {
+		return
+	}
+
+	ig := cfg.IntervalGenerator(nil)
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if !assert.Equal(t, expected, ig.Next(), `intervals should match`) {
+			return
+		}
+	}
+
+	buf, err := json.Marshal(cfg)
+	if !assert.NoError(t, err, `json.Marshal should succeed`) {
+		return
+	}
+	if !assert.JSONEq(t, `{"type": "exponential", "min_interval": "1s", "multiplier": 2, "max_retries": 3}`, string(buf), `configuration should round trip`) {
+		return
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	testcases := []string{
+		``,
+		`{}`,
+		`{"type": "linear"}`,
+		`{"type": "constant", "intervl": "1s"}`,
+		`{"type": "constant", "interval": "soon"}`,
+		`{"type": "constant", "interval": "-1s"}`,
+		`{"type": "constant", "multiplier": 2}`,
+		`{"type": "exponential", "multiplier": 0.5}`,
+		`{"type": "exponential", "min_interval": "1m", "max_interval": "1s"}`,
+		`{"type": "exponential", "jitter_factor": 1.5}`,
+		`{"type": "exponential", "max_retries": -1}`,
+		`{"type": "null", "max_retries": 1}`,
+		`{"type": "null"} {"type": "null"}`,
+	}
+
+	for _, tc := range testcases {
+		_, err := backoffconfig.Parse(strings.NewReader(tc))
+		if !assert.Error(t, err, `Parse should fail for %q`, tc) {
+			return
+		}
+	}
+
+	// embedded configurations are validated explicitly
+	var cfg struct {
+		Retry backoffconfig.Config `json:"retry"`
+	}
+	if !assert.NoError(t, json.Unmarshal([]byte(`{"retry": {"type": "constant", "multiplier": 2}}`), &cfg), `json.Unmarshal should succeed`) {
+		return
+	}
+	if !assert.Error(t, cfg.Retry.Validate(), `Validate should fail`) {
+		return
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	testcases := []struct {
+		Config   string
+		Attempts int
+	}{
+		{Config: `{"type": "null"}`, Attempts: 1},
+		{Config: `{"type": "constant", "interval": "1ms", "max_retries": 2}`, Attempts: 3},
+		{Config: `{"type": "exponential", "min_interval": "1ms", "max_interval": "2ms", "max_retries": 3}`, Attempts: 4},
+	}
+
+	for _, tc := range testcases {
+		cfg, err := backoffconfig.Parse(strings.NewReader(tc.Config))
+		if !assert.NoError(t, err, `Parse should succeed for %q`, tc.Config) {
+			return
+		}
+
+		o := &stopObserver{stopped: make(chan struct{})}
+		p := cfg.Policy(backoff.WithObserver(o))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		c := p.Start(ctx)
+		var n int
+		for backoff.Continue(c) {
+			n++
+		}
+		cancel()
+		if !assert.Equal(t, tc.Attempts, n, `number of attempts for %q`, tc.Config) {
+			return
+		}
+
+		select {
+		case <-o.stopped:
+		case <-time.After(time.Second):
+			assert.Fail(t, `options should be passed to the policy`, `observer of %q was not notified`, tc.Config)
+			return
+		}
+	}
+}
+
+type stopObserver struct {
+	backoff.NopObserver
+	stopped chan struct{}
+}
+
+func (o *stopObserver) OnStop() {
+	close(o.stopped)
+}
@@ -0,0 +1,87 @@
+// Package backoffdebug serves the list of live backoff controllers
+// via HTTP, in the same spirit as net/http/pprof.
+//
+// Importing this package enables controller tracking (see
+// backoff.TrackControllers), and registers the handler at
+// /debug/backoff in http.DefaultServeMux:
+//
+//	import _ "github.com/lestrrat-go/backoff/v2/backoffdebug"
+//
+// If you are not using http.DefaultServeMux, register Handler
+// in your own mux. Use backoff.WithName to tell the controllers apart.
+//
+// The handler responds with a plain text table by default. Specify
+// "?format=json" to receive the list as JSON.
+package backoffdebug
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+func init() {
+	backoff.TrackControllers(true)
+	http.Handle("/debug/backoff", Handler())
+}
+
+// Handler returns an http.Handler that lists the live controllers.
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	list := backoff.LiveControllers()
+	if r.FormValue("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = WriteText(w, list, time.Now())
+}
+
+// WriteText writes the list of controllers as a plain text table.
+// Durations such as the age of each controller are computed relative
+// to `now`.
+func WriteText(w io.Writer, list []backoff.ControllerInfo, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d live controllers\n\n", len(list))
+	fmt.Fprintln(tw, "ID\tNAME\tPOLICY\tATTEMPT\tMAX RETRIES\tNEXT ATTEMPT\tAGE")
+	for _, info := range list {
+		name := info.Name
+		if name == "" {
+			name = "-"
+		}
+
+		maxRetries := "unlimited"
+		switch {
+		case info.MaxRetries > 0:
+			maxRetries = fmt.Sprintf("%d", info.MaxRetries)
+		case info.MaxRetries < 0:
+			maxRetries = "none"
+		}
+
+		next := "-"
+		if !info.NextAttempt.IsZero() {
+			next = fmt.Sprintf("%s (in %s)", info.NextAttempt.Format(time.RFC3339), info.NextAttempt.Sub(now).Round(time.Millisecond))
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			info.ID,
+			name,
+			info.Policy,
+			info.Attempts,
+			maxRetries,
+			next,
+			now.Sub(info.Started).Round(time.Millisecond),
+		)
+	}
+	return tw.Flush()
+}
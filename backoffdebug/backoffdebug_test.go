@@ -0,0 +1,117 @@
+package backoffdebug_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffdebug"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := backoff.Exponential(
+		backoff.WithName("fetch-upstream"),
+		backoff.WithMinInterval(time.Hour),
+		backoff.WithMaxInterval(time.Hour),
+	).Start(ctx)
+	if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+		return
+	}
+	// the attempt fails, so the controller waits for the next one
+	backoff.Failure(c, nil)
+
+	t.Run("Text", func(t *testing.T) {
+		// registered in http.DefaultServeMux by init()
+		srv := httptest.NewServer(http.DefaultServeMux)
+		defer srv.Close()
+
+		res, err := srv.Client().Get(srv.URL + "/debug/backoff")
+		if !assert.NoError(t, err, `GET should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(res.Body)
+		if !assert.Equal(t, http.StatusOK, res.StatusCode, `status should be 200`) {
+			return
+		}
+		if !assert.Contains(t, buf.String(), "fetch-upstream", `output should contain the name`) {
+			return
+		}
+		if !assert.Contains(t, buf.String(), "exponential(min=1h0m0s, max=1h0m0s, multiplier=1.5)", `output should contain the policy`) {
+			return
+		}
+	})
+	t.Run("JSON", func(t *testing.T) {
+		var list []backoff.ControllerInfo
+		if !assert.Eventually(t, func() bool {
+			rec := httptest.NewRecorder()
+			backoffdebug.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/backoff?format=json", nil))
+			list = nil
+			if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+				return false
+			}
+			return len(list) == 1 && !list[0].NextAttempt.IsZero()
+		}, time.Second, time.Millisecond, `controller should be listed`) {
+			return
+		}
+
+		if !assert.Equal(t, "fetch-upstream", list[0].Name, `name should match`) {
+			return
+		}
+		if !assert.Equal(t, 1, list[0].Attempts, `attempts should match`) {
+			return
+		}
+	})
+}
+
+func TestWriteText(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	list := []backoff.ControllerInfo{
+		{
+			ID:          1,
+			Name:        "a",
+			Policy:      "constant(interval=1s)",
+			Attempts:    2,
+			MaxRetries:  10,
+			NextAttempt: now.Add(500 * time.Millisecond),
+			Started:     now.Add(-3 * time.Second),
+		},
+		{
+			ID:         2,
+			Policy:     "null",
+			MaxRetries: -1,
+			Started:    now,
+		},
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, backoffdebug.WriteText(&buf, list, now), `WriteText should succeed`) {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !assert.Len(t, lines, 5, `output should have a summary, a header and two rows`) {
+		return
+	}
+	if !assert.Equal(t, "2 live controllers", lines[0], `summary should match`) {
+		return
+	}
+	if !assert.Equal(t, []string{"1", "a", "constant(interval=1s)", "2", "10", "2021-03-01T12:00:00Z", "(in", "500ms)", "3s"}, strings.Fields(lines[3]), `first row should match`) {
+		return
+	}
+	if !assert.Equal(t, []string{"2", "-", "null", "0", "none", "-", "0s"}, strings.Fields(lines[4]), `second row should match`) {
+		return
+	}
+}
@@ -0,0 +1,23 @@
+module github.com/lestrrat-go/backoff/v2/backoffgrpc
+
+go 1.25.0
+
+replace github.com/lestrrat-go/backoff/v2 => ../
+
+require (
+	github.com/lestrrat-go/backoff/v2 v2.1.0
+	github.com/lestrrat-go/option v1.0.1
+	github.com/stretchr/testify v1.6.1
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
@@ -0,0 +1,183 @@
+// Package backoffgrpc provides gRPC client interceptors that retry
+// calls according to a backoff.Policy
+package backoffgrpc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// PreviousAttemptsKey is the outgoing metadata key that carries the
+	// number of attempts that preceded the current one. It is only set
+	// for retries.
+	PreviousAttemptsKey = "grpc-previous-rpc-attempts"
+
+	// PushbackKey is the trailer metadata key that servers may use to
+	// tell the client how many milliseconds to wait before retrying.
+	// A negative or malformed value means that the client should not
+	// retry at all.
+	PushbackKey = "grpc-retry-pushback-ms"
+)
+
+var defaultCodes = []codes.Code{
+	codes.Unavailable,
+	codes.ResourceExhausted,
+}
+
+type config struct {
+	codes             map[codes.Code]struct{}
+	perAttemptTimeout time.Duration
+}
+
+func newConfig(options []Option) *config {
+	list := defaultCodes
+	var perAttemptTimeout time.Duration
+	for _, option := range options {
+		switch option.Ident() {
+		case identCodes{}:
+			list = option.Value().([]codes.Code)
+		case identPerAttemptTimeout{}:
+			perAttemptTimeout = option.Value().(time.Duration)
+		}
+	}
+
+	m := make(map[codes.Code]struct{}, len(list))
+	for _, code := range list {
+		m[code] = struct{}{}
+	}
+	return &config{
+		codes:             m,
+		perAttemptTimeout: perAttemptTimeout,
+	}
+}
+
+// retryable returns true if the error returned by an attempt may be
+// retried. Attempts that failed because of the per-attempt timeout are
+// retried as long as the call itself has not been cancelled.
+func (cfg *config) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	code := status.Code(err)
+	if code == codes.DeadlineExceeded && cfg.perAttemptTimeout > 0 {
+		return true
+	}
+	_, ok := cfg.codes[code]
+	return ok
+}
+
+// metadataContext adds the number of previous attempts to the outgoing
+// metadata of the context
+func metadataContext(ctx context.Context, attempts int) context.Context {
+	if attempts > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, PreviousAttemptsKey, strconv.Itoa(attempts))
+	}
+	return ctx
+}
+
+// attemptContext creates the context used for a single unary attempt
+func (cfg *config) attemptContext(ctx context.Context, attempts int) (context.Context, context.CancelFunc) {
+	ctx = metadataContext(ctx, attempts)
+	if cfg.perAttemptTimeout > 0 {
+		return context.WithTimeout(ctx, cfg.perAttemptTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// retryAt inspects the trailer of a failed attempt, and returns the
+// time before which the next attempt should not be made. If the server
+// asked us not to retry, or if waiting would exceed the deadline of the
+// call, false is returned.
+func retryAt(ctx context.Context, trailer metadata.MD) (time.Time, bool) {
+	var at time.Time
+	if values := trailer.Get(PushbackKey); len(values) > 0 {
+		ms, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil || ms < 0 {
+			return at, false
+		}
+		at = time.Now().Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && at.After(deadline) {
+		return at, false
+	}
+	return at, true
+}
+
+// wait blocks until the given time is reached, or the context is done
+func wait(ctx context.Context, at time.Time) error {
+	d := backoff.ScaleDuration(time.Until(at))
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// finalError returns the error to be reported when the backoff has ended
+// without a successful attempt
+func finalError(ctx context.Context, err error) error {
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return status.Error(codes.Unavailable, `backoffgrpc: backoff ended before any attempt was made`)
+}
+
+// UnaryClientInterceptor creates an interceptor that retries unary calls
+// that fail with retryable status codes, according to the given policy.
+//
+// Retries stop when the deadline of the call is reached. If the server
+// sends `grpc-retry-pushback-ms` in its trailer, the next attempt is
+// delayed accordingly, or not made at all if the value is negative.
+func UnaryClientInterceptor(policy backoff.Policy, options ...Option) grpc.UnaryClientInterceptor {
+	cfg := newConfig(options)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		cctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var err error
+		var attempts int
+		var notBefore time.Time
+		c := policy.Start(cctx)
+		for backoff.Continue(c) {
+			if werr := wait(ctx, notBefore); werr != nil {
+				return finalError(ctx, err)
+			}
+
+			var trailer metadata.MD
+			actx, acancel := cfg.attemptContext(ctx, attempts)
+			err = invoker(actx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+			acancel()
+			attempts++
+
+			if err == nil || !cfg.retryable(ctx, err) {
+				return err
+			}
+
+			var ok bool
+			if notBefore, ok = retryAt(ctx, trailer); !ok {
+				return err
+			}
+		}
+		return finalError(ctx, err)
+	}
+}
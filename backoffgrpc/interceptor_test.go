@@ -0,0 +1,366 @@
+package backoffgrpc_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffgrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// flakyHealth fails the first `failures` calls with `code`, optionally
+// sending a pushback value in the trailer
+type flakyHealth struct {
+	healthpb.UnimplementedHealthServer
+
+	code     codes.Code
+	failures int
+	pushback string
+
+	mu       sync.Mutex
+	calls    int
+	attempts []string // values of grpc-previous-rpc-attempts
+}
+
+func (h *flakyHealth) fail(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.calls++
+	md, _ := metadata.FromIncomingContext(ctx)
+	h.attempts = append(h.attempts, append(md.Get(backoffgrpc.PreviousAttemptsKey), "")[0])
+	if h.calls > h.failures {
+		return nil
+	}
+	if h.pushback != "" {
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(backoffgrpc.PushbackKey, h.pushback))
+	}
+	return status.Error(h.code, `flaky`)
+}
+
+func (h *flakyHealth) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if err := h.fail(ctx); err != nil {
+		return nil, err
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (h *flakyHealth) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	if err := h.fail(stream.Context()); err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *flakyHealth) Calls() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls
+}
+
+func setup(t *testing.T, h *flakyHealth, policy backoff.Policy, options ...backoffgrpc.Option) healthpb.HealthClient {
+	t.Helper()
+
+	l := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, h)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(backoffgrpc.UnaryClientInterceptor(policy, options...)),
+		grpc.WithStreamInterceptor(backoffgrpc.StreamClientInterceptor(policy, options...)),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient failed: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func newPolicy(retries int) backoff.Policy {
+	return backoff.Constant(
+		backoff.WithInterval(10*time.Millisecond),
+		backoff.WithMaxRetries(retries),
+	)
+}
+
+func TestUnary(t *testing.T) {
+	t.Run("Retry until success", func(t *testing.T) {
+		h := &flakyHealth{code: codes.Unavailable, failures: 2}
+		cl := setup(t, h, newPolicy(5))
+
+		res, err := cl.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if !assert.NoError(t, err, `Check should succeed`) {
+			return
+		}
+		if !assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status) {
+			return
+		}
+		if !assert.Equal(t, []string{"", "1", "2"}, h.attempts, `previous attempts should be sent in metadata`) {
+			return
+		}
+	})
+	t.Run("Non-retryable code", func(t *testing.T) {
+		h := &flakyHealth{code: codes.InvalidArgument, failures: 2}
+		cl := setup(t, h, newPolicy(5))
+
+		_, err := cl.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if !assert.Equal(t, codes.InvalidArgument, status.Code(err), `error should be returned as is`) {
+			return
+		}
+		if !assert.Equal(t, 1, h.Calls(), `should not retry`) {
+			return
+		}
+	})
+	t.Run("Custom codes", func(t *testing.T) {
+		h := &flakyHealth{code: codes.Aborted, failures: 2}
+		cl := setup(t, h, newPolicy(5), backoffgrpc.WithCodes(codes.Aborted))
+
+		_, err := cl.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if !assert.NoError(t, err, `Check should succeed`) {
+			return
+		}
+	})
+	t.Run("Retries exhausted", func(t *testing.T) {
+		h := &flakyHealth{code: codes.ResourceExhausted, failures: 100}
+		cl := setup(t, h, newPolicy(2))
+
+		_, err := cl.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if !assert.Equal(t, codes.ResourceExhausted, status.Code(err), `last error should be returned`) {
+			return
+		}
+		if !assert.Equal(t, 3, h.Calls(), `initial + 2 retries`) {
+			return
+		}
+	})
+	t.Run("Negative pushback stops retries", func(t *testing.T) {
+		h := &flakyHealth{code: codes.Unavailable, failures: 2, pushback: "-1"}
+		cl := setup(t, h, newPolicy(5))
+
+		_, err := cl.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if !assert.Equal(t, codes.Unavailable, status.Code(err)) {
+			return
+		}
+		if !assert.Equal(t, 1, h.Calls(), `should not retry`) {
+			return
+		}
+	})
+	t.Run("Pushback delays retries", func(t *testing.T) {
+		h := &flakyHealth{code: codes.Unavailable, failures: 1, pushback: strconv.Itoa(300)}
+		cl := setup(t, h, newPolicy(5))
+
+		start := time.Now()
+		_, err := cl.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if !assert.NoError(t, err, `Check should succeed`) {
+			return
+		}
+		if !assert.True(t, time.Since(start) >= 300*time.Millisecond, `should have waited for pushback`) {
+			return
+		}
+	})
+	t.Run("Pushback beyond deadline", func(t *testing.T) {
+		h := &flakyHealth{code: codes.Unavailable, failures: 1, pushback: strconv.Itoa(60000)}
+		cl := setup(t, h, newPolicy(5))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		start := time.Now()
+		_, err := cl.Check(ctx, &healthpb.HealthCheckRequest{})
+		if !assert.Equal(t, codes.Unavailable, status.Code(err)) {
+			return
+		}
+		if !assert.True(t, time.Since(start) < 500*time.Millisecond, `should give up immediately`) {
+			return
+		}
+	})
+	t.Run("Deadline", func(t *testing.T) {
+		h := &flakyHealth{code: codes.Unavailable, failures: 1000}
+		cl := setup(t, h, newPolicy(0))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := cl.Check(ctx, &healthpb.HealthCheckRequest{})
+		if !assert.Error(t, err, `Check should fail`) {
+			return
+		}
+		if !assert.True(t, time.Since(start) >= 150*time.Millisecond && h.Calls() > 2, `should keep retrying until the deadline`) {
+			return
+		}
+	})
+}
+
+func TestStream(t *testing.T) {
+	t.Run("Retry until success", func(t *testing.T) {
+		h := &flakyHealth{code: codes.Unavailable, failures: 2}
+		cl := setup(t, h, newPolicy(5))
+
+		stream, err := cl.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "foo"})
+		if !assert.NoError(t, err, `Watch should succeed`) {
+			return
+		}
+
+		var count int
+		for {
+			_, err := stream.Recv()
+			if err != nil {
+				if !assert.Equal(t, io.EOF, err, `stream should end cleanly`) {
+					return
+				}
+				break
+			}
+			count++
+		}
+		if !assert.Equal(t, 3, count, `should receive all messages`) {
+			return
+		}
+		if !assert.Equal(t, []string{"", "1", "2"}, h.attempts, `previous attempts should be sent in metadata`) {
+			return
+		}
+	})
+	t.Run("Retries exhausted", func(t *testing.T) {
+		h := &flakyHealth{code: codes.Unavailable, failures: 100}
+		cl := setup(t, h, newPolicy(2))
+
+		stream, err := cl.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		if !assert.NoError(t, err, `Watch should succeed`) {
+			return
+		}
+		_, err = stream.Recv()
+		if !assert.Equal(t, codes.Unavailable, status.Code(err), `last error should be returned`) {
+			return
+		}
+		if !assert.Equal(t, 3, h.Calls(), `initial + 2 retries`) {
+			return
+		}
+	})
+}
+
+// fakeStream is an underlying stream whose RecvMsg and SendMsg return
+// the given errors
+type fakeStream struct {
+	grpc.ClientStream
+	ctx     context.Context
+	recvErr error
+	sendErr error
+}
+
+func (s *fakeStream) Context() context.Context     { return s.ctx }
+func (s *fakeStream) Trailer() metadata.MD         { return nil }
+func (s *fakeStream) CloseSend() error             { return nil }
+func (s *fakeStream) SendMsg(interface{}) error    { return s.sendErr }
+func (s *fakeStream) RecvMsg(interface{}) error    { return s.recvErr }
+func (s *fakeStream) Header() (metadata.MD, error) { return nil, nil }
+
+func TestStreamReplay(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, `unavailable`)
+	t.Run("Failed replay is released", func(t *testing.T) {
+		// the first stream fails on receipt, the second one while the
+		// messages are replayed, and the third one succeeds
+		var streams []*fakeStream
+		streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+			s := &fakeStream{ctx: ctx}
+			switch len(streams) {
+			case 0:
+				s.recvErr = unavailable
+			case 1:
+				s.sendErr = unavailable
+			}
+			streams = append(streams, s)
+			return s, nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		interceptor := backoffgrpc.StreamClientInterceptor(newPolicy(5))
+		stream, err := interceptor(ctx, &grpc.StreamDesc{}, nil, "/test", streamer)
+		if !assert.NoError(t, err, `stream should be created`) {
+			return
+		}
+		if !assert.NoError(t, stream.SendMsg("hello"), `SendMsg should succeed`) {
+			return
+		}
+		if !assert.NoError(t, stream.RecvMsg(nil), `RecvMsg should succeed after retrying`) {
+			return
+		}
+
+		if !assert.Len(t, streams, 3, `two retries should be made`) {
+			return
+		}
+		for i, s := range streams[:2] {
+			if !assert.Error(t, s.ctx.Err(), `stream #%d should be released`, i+1) {
+				return
+			}
+		}
+		if !assert.NoError(t, streams[2].ctx.Err(), `committed stream should not be released`) {
+			return
+		}
+	})
+	t.Run("SendMsg while waiting", func(t *testing.T) {
+		var calls int
+		streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+			calls++
+			if calls == 1 {
+				return &fakeStream{ctx: ctx, recvErr: unavailable}, nil
+			}
+			return &fakeStream{ctx: ctx}, nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		interceptor := backoffgrpc.StreamClientInterceptor(backoff.Constant(
+			backoff.WithInterval(500*time.Millisecond),
+			backoff.WithMaxRetries(1),
+		))
+		stream, err := interceptor(ctx, &grpc.StreamDesc{}, nil, "/test", streamer)
+		if !assert.NoError(t, err, `stream should be created`) {
+			return
+		}
+
+		received := make(chan error, 1)
+		go func() { received <- stream.RecvMsg(nil) }()
+
+		// RecvMsg is waiting for the retry, which must not block SendMsg
+		time.Sleep(50 * time.Millisecond)
+		sent := make(chan error, 1)
+		go func() { sent <- stream.SendMsg("hello") }()
+		select {
+		case err := <-sent:
+			if !assert.NoError(t, err, `SendMsg should succeed`) {
+				return
+			}
+		case <-time.After(250 * time.Millisecond):
+			assert.Fail(t, `SendMsg should not block while RecvMsg is waiting`)
+			return
+		}
+
+		if !assert.NoError(t, <-received, `RecvMsg should succeed after retrying`) {
+			return
+		}
+	})
+}
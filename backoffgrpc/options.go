@@ -0,0 +1,31 @@
+package backoffgrpc
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/option"
+	"google.golang.org/grpc/codes"
+)
+
+type identCodes struct{}
+type identPerAttemptTimeout struct{}
+
+// Option is an option that can be passed to UnaryClientInterceptor
+// and StreamClientInterceptor
+type Option = option.Interface
+
+// WithCodes specifies the status codes that are considered retryable.
+// By default Unavailable and ResourceExhausted are retried.
+func WithCodes(v ...codes.Code) Option {
+	return option.New(identCodes{}, v)
+}
+
+// WithPerAttemptTimeout specifies the timeout for each individual
+// attempt. The overall deadline of the call is still respected.
+// By default each attempt may use up all of the remaining time.
+//
+// For streams, this timeout applies to establishing the stream and
+// receiving the first response.
+func WithPerAttemptTimeout(v time.Duration) Option {
+	return option.New(identPerAttemptTimeout{}, v)
+}
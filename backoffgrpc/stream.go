@@ -0,0 +1,279 @@
+package backoffgrpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// StreamClientInterceptor creates an interceptor that retries streaming
+// calls that fail with retryable status codes, according to the given
+// policy.
+//
+// A stream is only retried until the first response message has been
+// received. Until then, all messages sent on the stream are kept, and
+// replayed on the new stream when a retry is performed. Callers must
+// therefore not modify messages after passing them to SendMsg.
+//
+// Deadlines and `grpc-retry-pushback-ms` are handled in the same way
+// as UnaryClientInterceptor.
+func StreamClientInterceptor(policy backoff.Policy, options ...Option) grpc.StreamClientInterceptor {
+	cfg := newConfig(options)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		// The underlying streams are derived from cctx, and the controller
+		// from pctx, so that the controller can be stopped once the stream
+		// has been committed while the stream itself keeps running
+		cctx, cancel := context.WithCancel(ctx)
+		pctx, stop := context.WithCancel(cctx)
+		s := &retryStream{
+			cancel:     cancel,
+			cc:         cc,
+			cctx:       cctx,
+			cfg:        cfg,
+			controller: policy.Start(pctx),
+			ctx:        ctx,
+			desc:       desc,
+			method:     method,
+			mu:         &sync.Mutex{},
+			opts:       opts,
+			stop:       stop,
+			streamer:   streamer,
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.establish(nil); err != nil {
+			s.fail()
+			return nil, err
+		}
+		return s, nil
+	}
+}
+
+type retryStream struct {
+	attempts     int
+	attemptTimer *time.Timer
+	broken       bool // the current stream returned io.EOF from SendMsg
+	buffer       []interface{}
+	cancel       func() // cancels cctx
+	cancelStream func()
+	cc           *grpc.ClientConn
+	cctx         context.Context // parent of the contexts of the underlying streams
+	cfg          *config
+	closeSent    bool
+	committed    bool // a response has been received, no more retries
+	controller   backoff.Controller
+	ctx          context.Context
+	desc         *grpc.StreamDesc
+	method       string
+	mu           *sync.Mutex
+	opts         []grpc.CallOption
+	stop         func() // stops the controller
+	stream       grpc.ClientStream
+	streamer     grpc.Streamer
+	timedOut     *int32 // set when the per-attempt timeout fires
+}
+
+// establish creates a new underlying stream, replaying any messages that
+// have been sent so far. `lastErr` is the error that caused the previous
+// stream to fail, if any. Must be called with the lock held. The lock is
+// released while waiting between attempts, during which messages sent
+// on the stream are only buffered
+func (s *retryStream) establish(lastErr error) error {
+	var notBefore time.Time
+	if lastErr != nil {
+		var ok bool
+		if notBefore, ok = retryAt(s.ctx, s.stream.Trailer()); !ok {
+			return lastErr
+		}
+	}
+
+	err := lastErr
+	for {
+		// A failed attempt may have created a stream before failing to
+		// replay the messages
+		s.releaseStream()
+		s.broken = true
+
+		s.mu.Unlock()
+		ok := backoff.Continue(s.controller) && wait(s.ctx, notBefore) == nil
+		s.mu.Lock()
+		if !ok {
+			break
+		}
+		notBefore = time.Time{}
+
+		err = s.attempt()
+		if err == nil || !s.cfg.retryable(s.ctx, err) {
+			return err
+		}
+	}
+	return finalError(s.ctx, err)
+}
+
+// attempt makes a single attempt at creating the underlying stream
+func (s *retryStream) attempt() error {
+	actx, cancel := context.WithCancel(metadataContext(s.cctx, s.attempts))
+	s.attempts++
+
+	stream, err := s.streamer(actx, s.desc, s.cc, s.method, s.opts...)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	s.stream = stream
+	s.cancelStream = cancel
+	s.broken = false
+	if s.cfg.perAttemptTimeout > 0 {
+		// The per-attempt timeout only applies until the first response
+		// is received, so context.WithTimeout cannot be used here
+		timedOut := new(int32)
+		s.timedOut = timedOut
+		s.attemptTimer = time.AfterFunc(s.cfg.perAttemptTimeout, func() {
+			atomic.StoreInt32(timedOut, 1)
+			cancel()
+		})
+	}
+
+	for _, m := range s.buffer {
+		if err := stream.SendMsg(m); err != nil {
+			if err == io.EOF {
+				// the actual error will be reported by RecvMsg
+				s.broken = true
+				return nil
+			}
+			return err
+		}
+	}
+	if s.closeSent {
+		return stream.CloseSend()
+	}
+	return nil
+}
+
+// releaseStream cancels the current underlying stream, if any
+func (s *retryStream) releaseStream() {
+	if s.attemptTimer != nil {
+		s.attemptTimer.Stop()
+		s.attemptTimer = nil
+	}
+	if s.cancelStream != nil {
+		s.cancelStream()
+		s.cancelStream = nil
+	}
+}
+
+// commit marks the stream as no longer retryable, and releases the
+// resources used for retrying
+func (s *retryStream) commit() {
+	if s.committed {
+		return
+	}
+	s.committed = true
+	s.buffer = nil
+	if s.attemptTimer != nil {
+		s.attemptTimer.Stop()
+		s.attemptTimer = nil
+	}
+	s.stop()
+}
+
+// fail commits the stream after it has failed for good, and releases
+// the underlying stream
+func (s *retryStream) fail() {
+	s.commit()
+	s.releaseStream()
+	s.cancel()
+}
+
+func (s *retryStream) retryable(err error) bool {
+	if s.timedOut != nil && atomic.LoadInt32(s.timedOut) == 1 && s.ctx.Err() == nil {
+		return true
+	}
+	return s.cfg.retryable(s.ctx, err)
+}
+
+func (s *retryStream) current() grpc.ClientStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream
+}
+
+func (s *retryStream) Header() (metadata.MD, error) {
+	return s.current().Header()
+}
+
+func (s *retryStream) Trailer() metadata.MD {
+	return s.current().Trailer()
+}
+
+func (s *retryStream) Context() context.Context {
+	return s.current().Context()
+}
+
+func (s *retryStream) CloseSend() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeSent = true
+	if s.broken {
+		return nil
+	}
+	return s.stream.CloseSend()
+}
+
+func (s *retryStream) SendMsg(m interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.committed {
+		return s.stream.SendMsg(m)
+	}
+
+	s.buffer = append(s.buffer, m)
+	if s.broken {
+		return nil
+	}
+	if err := s.stream.SendMsg(m); err != nil {
+		if err == io.EOF {
+			// The stream is broken, and the actual error will be reported
+			// by RecvMsg, at which point we may retry
+			s.broken = true
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *retryStream) RecvMsg(m interface{}) error {
+	for {
+		stream := s.current()
+		err := stream.RecvMsg(m)
+
+		s.mu.Lock()
+		if err == nil {
+			s.commit()
+			s.mu.Unlock()
+			return nil
+		}
+		if s.committed || err == io.EOF || !s.retryable(err) {
+			s.fail()
+			s.mu.Unlock()
+			return err
+		}
+
+		if err := s.establish(err); err != nil {
+			s.fail()
+			s.mu.Unlock()
+			return err
+		}
+		s.mu.Unlock()
+	}
+}
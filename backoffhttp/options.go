@@ -0,0 +1,91 @@
+package backoffhttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/option"
+)
+
+type identKeyFunc struct{}
+type identMaxRetryAfter struct{}
+type identRetryStatusCodes struct{}
+type identTTL struct{}
+type identTransport struct{}
+
+// TransportOption is an option that can be passed to NewTransport
+type TransportOption interface {
+	option.Interface
+	transportOption()
+}
+
+type transportOption struct {
+	option.Interface
+}
+
+func (*transportOption) transportOption() {}
+
+// RetryAfterOption is an option that can be passed to NewRetryAfter
+type RetryAfterOption interface {
+	option.Interface
+	retryAfterOption()
+}
+
+type retryAfterOption struct {
+	option.Interface
+}
+
+func (*retryAfterOption) retryAfterOption() {}
+
+// CommonOption is an option that can be passed to both NewTransport
+// and NewRetryAfter
+type CommonOption interface {
+	TransportOption
+	RetryAfterOption
+}
+
+type commonOption struct {
+	option.Interface
+}
+
+func (*commonOption) transportOption()  {}
+func (*commonOption) retryAfterOption() {}
+
+// WithTransport specifies the http.RoundTripper that actually performs
+// the requests. By default http.DefaultTransport is used.
+func WithTransport(v http.RoundTripper) TransportOption {
+	return &transportOption{option.New(identTransport{}, v)}
+}
+
+// WithRetryStatusCodes specifies the HTTP status codes that signal the
+// client to come back later.
+//
+// When passed to NewTransport, these are the status codes that cause a
+// request to be retried. By default 429, 502, 503, and 504 are retried.
+//
+// When passed to NewRetryAfter, these are the status codes for which a
+// `Retry-After` header is added. By default 429 and 503 are used.
+func WithRetryStatusCodes(v ...int) CommonOption {
+	return &commonOption{option.New(identRetryStatusCodes{}, v)}
+}
+
+// WithMaxRetryAfter specifies the maximum duration that the transport
+// is willing to wait when the server responds with a `Retry-After`
+// header. Longer values are truncated to this duration. By default
+// there is no limit, other than the request's context.
+func WithMaxRetryAfter(v time.Duration) TransportOption {
+	return &transportOption{option.New(identMaxRetryAfter{}, v)}
+}
+
+// WithKeyFunc specifies the function used to identify the client that
+// sent the request, such as an API token. By default the IP address
+// of the remote peer is used.
+func WithKeyFunc(v func(*http.Request) string) RetryAfterOption {
+	return &retryAfterOption{option.New(identKeyFunc{}, v)}
+}
+
+// WithTTL specifies how long the backoff state for a client is kept
+// after its last request. By default the state is kept for 10 minutes.
+func WithTTL(v time.Duration) RetryAfterOption {
+	return &retryAfterOption{option.New(identTTL{}, v)}
+}
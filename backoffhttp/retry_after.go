@@ -0,0 +1,218 @@
+package backoffhttp
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+const defaultTTL = 10 * time.Minute
+
+var defaultRetryAfterStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusServiceUnavailable,
+}
+
+type retryAfterState struct {
+	ig       backoff.IntervalGenerator
+	lastSeen time.Time
+}
+
+// RetryAfter is an HTTP middleware that advertises when clients should
+// come back via the `Retry-After` header.
+//
+// Each client (as identified by the function given to WithKeyFunc) is
+// assigned its own interval generator. Every time a response with one of
+// the configured status codes is sent to that client, the next interval
+// from its generator is advertised in the `Retry-After` header.
+// Using a generator with jitter enabled spreads clients out, instead
+// of having all of them come back at the same time.
+//
+// The state for a client is discarded when a successful (< 400) response
+// is sent to it, or when it has not been seen for the duration specified
+// by WithTTL.
+type RetryAfter struct {
+	keyFunc      func(*http.Request) string
+	mu           *sync.Mutex
+	newGenerator func() backoff.IntervalGenerator
+	lastSweep    time.Time
+	states       map[string]*retryAfterState
+	statusCodes  map[int]struct{}
+	ttl          time.Duration
+}
+
+// NewRetryAfter creates a new RetryAfter middleware. The `newGenerator`
+// function is called to create the backoff state for each client, e.g.
+//
+//	backoffhttp.NewRetryAfter(func() backoff.IntervalGenerator {
+//	  return backoff.NewExponentialInterval(
+//	    backoff.WithMinInterval(time.Second),
+//	    backoff.WithJitterFactor(0.2),
+//	  )
+//	})
+func NewRetryAfter(newGenerator func() backoff.IntervalGenerator, options ...RetryAfterOption) *RetryAfter {
+	keyFunc := remoteIP
+	codes := defaultRetryAfterStatusCodes
+	ttl := defaultTTL
+	for _, option := range options {
+		switch option.Ident() {
+		case identKeyFunc{}:
+			keyFunc = option.Value().(func(*http.Request) string)
+		case identRetryStatusCodes{}:
+			codes = option.Value().([]int)
+		case identTTL{}:
+			ttl = option.Value().(time.Duration)
+		}
+	}
+
+	statusCodes := make(map[int]struct{}, len(codes))
+	for _, code := range codes {
+		statusCodes[code] = struct{}{}
+	}
+
+	return &RetryAfter{
+		keyFunc:      keyFunc,
+		mu:           &sync.Mutex{},
+		newGenerator: newGenerator,
+		lastSweep:    time.Now(),
+		states:       make(map[string]*retryAfterState),
+		statusCodes:  statusCodes,
+		ttl:          ttl,
+	}
+}
+
+// Wrap wraps the given handler, adding `Retry-After` headers to its
+// responses as necessary.
+func (m *RetryAfter) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &retryAfterWriter{
+			ResponseWriter: w,
+			key:            m.keyFunc(r),
+			middleware:     m,
+		}
+		h.ServeHTTP(rw, r)
+
+		// net/http sends 200 OK for handlers that do not write anything
+		if !rw.wroteHeader {
+			m.reset(rw.key)
+		}
+	})
+}
+
+// next returns the duration that the client should wait before
+// retrying, advancing its backoff state
+func (m *RetryAfter) next(key string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	st, ok := m.states[key]
+	if !ok {
+		st = &retryAfterState{ig: m.newGenerator()}
+		m.states[key] = st
+	}
+	st.lastSeen = now
+	return st.ig.Next()
+}
+
+// reset discards the backoff state for the client
+func (m *RetryAfter) reset(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.states, key)
+	m.sweep(time.Now())
+}
+
+// sweep removes states that have not been used for longer than the TTL.
+// It runs at most once per TTL. Must be called with the lock held
+func (m *RetryAfter) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.ttl {
+		return
+	}
+	m.lastSweep = now
+
+	for key, st := range m.states {
+		if now.Sub(st.lastSeen) >= m.ttl {
+			delete(m.states, key)
+		}
+	}
+}
+
+type retryAfterWriter struct {
+	http.ResponseWriter
+	key         string
+	middleware  *RetryAfter
+	wroteHeader bool
+}
+
+func (w *retryAfterWriter) WriteHeader(code int) {
+	// Informational responses such as 103 Early Hints may precede the
+	// actual response. As in net/http, 101 is the actual response
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+
+	m := w.middleware
+	if _, ok := m.statusCodes[code]; ok {
+		if w.Header().Get("Retry-After") == "" {
+			w.Header().Set("Retry-After", formatRetryAfter(m.next(w.key)))
+		}
+	} else if code < 400 {
+		m.reset(w.key)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *retryAfterWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *retryAfterWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the original ResponseWriter, so that
+// http.ResponseController can reach the features that are not forwarded
+// here, such as Hijack or SetWriteDeadline.
+func (w *retryAfterWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// formatRetryAfter formats the duration as the number of seconds,
+// rounded up, with a minimum of 1 second.
+func formatRetryAfter(d time.Duration) string {
+	secs := int64(math.Ceil(d.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return strconv.FormatInt(secs, 10)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
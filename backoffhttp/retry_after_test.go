@@ -0,0 +1,150 @@
+package backoffhttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryAfter(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+
+	m := backoffhttp.NewRetryAfter(
+		func() backoff.IntervalGenerator {
+			return backoff.NewExponentialInterval(
+				backoff.WithMinInterval(time.Second),
+				backoff.WithMultiplier(2),
+			)
+		},
+		backoffhttp.WithKeyFunc(func(r *http.Request) string {
+			return r.Header.Get("X-Api-Token")
+		}),
+	)
+	srv := m.Wrap(h)
+
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Token", token)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, expected := range []string{"1", "2", "4", "8"} {
+		rec := request("alice")
+		if !assert.Equal(t, expected, rec.Header().Get("Retry-After"), `Retry-After should back off`) {
+			return
+		}
+	}
+
+	// Other clients are tracked independently
+	if !assert.Equal(t, "1", request("bob").Header().Get("Retry-After"), `Retry-After should be independent per client`) {
+		return
+	}
+
+	// Successful responses reset the state
+	status = http.StatusOK
+	if !assert.Empty(t, request("alice").Header().Get("Retry-After"), `Retry-After should not be set for successful responses`) {
+		return
+	}
+	status = http.StatusTooManyRequests
+	if !assert.Equal(t, "1", request("alice").Header().Get("Retry-After"), `Retry-After should be reset after success`) {
+		return
+	}
+
+	// Status codes that are not configured are left alone
+	status = http.StatusInternalServerError
+	if !assert.Empty(t, request("alice").Header().Get("Retry-After"), `Retry-After should not be set for 500`) {
+		return
+	}
+	status = http.StatusServiceUnavailable
+	if !assert.Equal(t, "2", request("alice").Header().Get("Retry-After"), `500 should not reset the state`) {
+		return
+	}
+}
+
+func TestRetryAfterJitter(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	m := backoffhttp.NewRetryAfter(func() backoff.IntervalGenerator {
+		return backoff.NewConstantInterval(
+			backoff.WithInterval(30*time.Second),
+			backoff.WithJitterFactor(0.5),
+		)
+	})
+	srv := m.Wrap(h)
+
+	seen := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		seen[rec.Header().Get("Retry-After")] = struct{}{}
+	}
+	if !assert.True(t, len(seen) > 1, `jitter should spread out Retry-After values`) {
+		return
+	}
+}
+
+func TestRetryAfterResponseController(t *testing.T) {
+	m := backoffhttp.NewRetryAfter(func() backoff.IntervalGenerator {
+		return backoff.NewConstantInterval(backoff.WithInterval(time.Second))
+	})
+
+	var err error
+	srv := httptest.NewServer(m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// only available on the original ResponseWriter
+		err = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Minute))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+	defer srv.Close()
+
+	res, rerr := http.Get(srv.URL)
+	if !assert.NoError(t, rerr, `request should succeed`) {
+		return
+	}
+	res.Body.Close()
+
+	if !assert.NoError(t, err, `ResponseController should reach the original ResponseWriter`) {
+		return
+	}
+	if !assert.Equal(t, "1", res.Header.Get("Retry-After"), `Retry-After should still be set`) {
+		return
+	}
+}
+
+func TestRetryAfterInformational(t *testing.T) {
+	m := backoffhttp.NewRetryAfter(func() backoff.IntervalGenerator {
+		return backoff.NewConstantInterval(backoff.WithInterval(time.Second))
+	})
+
+	srv := httptest.NewServer(m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if !assert.NoError(t, err, `request should succeed`) {
+		return
+	}
+	res.Body.Close()
+
+	if !assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode, `final status should be returned`) {
+		return
+	}
+	if !assert.Equal(t, "1", res.Header.Get("Retry-After"), `Retry-After should be set on the final response`) {
+		return
+	}
+}
@@ -0,0 +1,215 @@
+// Package backoffhttp provides HTTP integrations for backoff policies: an
+// http.RoundTripper that retries requests according to a backoff.Policy,
+// and a server middleware that advertises `Retry-After` to clients.
+package backoffhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// maxDrainBytes is the maximum number of bytes that are read from the
+// body of a discarded response so that the underlying connection can
+// be reused. Larger bodies are simply closed.
+const maxDrainBytes = 4096
+
+var defaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Transport is an http.RoundTripper that retries requests that fail
+// with connection errors, or whose responses carry one of the configured
+// status codes.
+//
+// Only requests that are safe to be repeated are retried: that is,
+// requests with idempotent methods (GET, HEAD, OPTIONS, TRACE, PUT,
+// DELETE), or requests carrying an `Idempotency-Key` or
+// `X-Idempotency-Key` header. Requests with a body must also provide
+// `Request.GetBody` (http.NewRequest does this for common body types)
+// so that the body can be rewound for subsequent attempts.
+//
+// When a response includes a `Retry-After` header, the next attempt is
+// not made before the time specified by the server.
+type Transport struct {
+	maxRetryAfter    time.Duration
+	policy           backoff.Policy
+	retryStatusCodes map[int]struct{}
+	transport        http.RoundTripper
+}
+
+// NewTransport creates a new Transport that retries requests according
+// to the given policy.
+func NewTransport(policy backoff.Policy, options ...TransportOption) *Transport {
+	var maxRetryAfter time.Duration
+	codes := defaultRetryStatusCodes
+	transport := http.DefaultTransport
+	for _, option := range options {
+		switch option.Ident() {
+		case identMaxRetryAfter{}:
+			maxRetryAfter = option.Value().(time.Duration)
+		case identRetryStatusCodes{}:
+			codes = option.Value().([]int)
+		case identTransport{}:
+			transport = option.Value().(http.RoundTripper)
+		}
+	}
+
+	retryStatusCodes := make(map[int]struct{}, len(codes))
+	for _, code := range codes {
+		retryStatusCodes[code] = struct{}{}
+	}
+
+	return &Transport{
+		maxRetryAfter:    maxRetryAfter,
+		policy:           policy,
+		retryStatusCodes: retryStatusCodes,
+		transport:        transport,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isReplayable(req) {
+		return t.transport.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var res *http.Response
+	var err error
+	var notBefore time.Time
+	var attempts int
+
+	c := t.policy.Start(cctx)
+	for backoff.Continue(c) {
+		if res != nil {
+			discard(res)
+			res = nil
+		}
+
+		if wait := backoff.ScaleDuration(time.Until(notBefore)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		r := req
+		if attempts > 0 {
+			r, err = rewind(req)
+			if err != nil {
+				return nil, err
+			}
+		}
+		attempts++
+
+		res, err = t.transport.RoundTrip(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, ok := t.retryStatusCodes[res.StatusCode]; !ok {
+			return res, nil
+		}
+		notBefore = t.retryAfter(res)
+	}
+
+	if ctx.Err() != nil {
+		if res != nil {
+			discard(res)
+		}
+		return nil, ctx.Err()
+	}
+	if res == nil && err == nil {
+		return nil, errors.New(`backoffhttp: backoff ended before any attempt was made`)
+	}
+	return res, err
+}
+
+// retryAfter returns the time before which the next attempt should not
+// be made, as specified by the `Retry-After` header. The zero value is
+// returned if there is no such header, or if it could not be parsed.
+func (t *Transport) retryAfter(res *http.Response) time.Time {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return time.Time{}
+	}
+
+	now := time.Now()
+	var at time.Time
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return time.Time{}
+		}
+		at = now.Add(time.Duration(secs) * time.Second)
+	} else if date, err := http.ParseTime(v); err == nil {
+		at = date
+	} else {
+		return time.Time{}
+	}
+
+	if t.maxRetryAfter > 0 && at.Sub(now) > t.maxRetryAfter {
+		at = now.Add(t.maxRetryAfter)
+	}
+	return at
+}
+
+func isReplayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	// Same headers that net/http recognizes when deciding whether or not
+	// a request can be retried on a new connection
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	if _, ok := req.Header["X-Idempotency-Key"]; ok {
+		return true
+	}
+	return false
+}
+
+// rewind creates a copy of the request with a fresh body, so that it
+// can be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return r, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = body
+	return r, nil
+}
+
+// discard drains and closes the body of a response that is not going
+// to be returned to the caller, so that the connection can be reused.
+func discard(res *http.Response) {
+	_, _ = io.CopyN(io.Discard, res.Body, maxDrainBytes)
+	res.Body.Close()
+}
@@ -0,0 +1,241 @@
+package backoffhttp_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPolicy(retries int) backoff.Policy {
+	return backoff.Constant(
+		backoff.WithInterval(10*time.Millisecond),
+		backoff.WithMaxRetries(retries),
+	)
+}
+
+// failN returns a handler that responds with `status` for the first `n`
+// requests, and then with 200 OK
+func failN(n int32, status int, count *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(count, 1) <= n {
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	t.Run("Retry on status code", func(t *testing.T) {
+		var count int32
+		srv := httptest.NewServer(failN(2, http.StatusServiceUnavailable, &count))
+		defer srv.Close()
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(5))}
+		res, err := cl.Get(srv.URL)
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		if !assert.Equal(t, http.StatusOK, res.StatusCode, `final response should be 200`) {
+			return
+		}
+		if !assert.Equal(t, int32(3), atomic.LoadInt32(&count), `server should have seen 3 requests`) {
+			return
+		}
+	})
+	t.Run("Non-retryable status code", func(t *testing.T) {
+		var count int32
+		srv := httptest.NewServer(failN(2, http.StatusInternalServerError, &count))
+		defer srv.Close()
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(5))}
+		res, err := cl.Get(srv.URL)
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		if !assert.Equal(t, http.StatusInternalServerError, res.StatusCode, `response should be returned as is`) {
+			return
+		}
+		if !assert.Equal(t, int32(1), atomic.LoadInt32(&count), `server should have seen 1 request`) {
+			return
+		}
+	})
+	t.Run("Custom status codes", func(t *testing.T) {
+		var count int32
+		srv := httptest.NewServer(failN(1, http.StatusInternalServerError, &count))
+		defer srv.Close()
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(5), backoffhttp.WithRetryStatusCodes(http.StatusInternalServerError))}
+		res, err := cl.Get(srv.URL)
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		if !assert.Equal(t, http.StatusOK, res.StatusCode, `final response should be 200`) {
+			return
+		}
+	})
+	t.Run("Retries exhausted", func(t *testing.T) {
+		var count int32
+		srv := httptest.NewServer(failN(100, http.StatusBadGateway, &count))
+		defer srv.Close()
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(2))}
+		res, err := cl.Get(srv.URL)
+		if !assert.NoError(t, err, `last response should be returned`) {
+			return
+		}
+		defer res.Body.Close()
+
+		if !assert.Equal(t, http.StatusBadGateway, res.StatusCode, `last response should be returned`) {
+			return
+		}
+		if !assert.Equal(t, int32(3), atomic.LoadInt32(&count), `server should have seen 3 requests`) {
+			return
+		}
+	})
+	t.Run("POST is not retried", func(t *testing.T) {
+		var count int32
+		srv := httptest.NewServer(failN(1, http.StatusServiceUnavailable, &count))
+		defer srv.Close()
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(5))}
+		res, err := cl.Post(srv.URL, "text/plain", bytes.NewBufferString(`hello`))
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		if !assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode, `response should be returned as is`) {
+			return
+		}
+		if !assert.Equal(t, int32(1), atomic.LoadInt32(&count), `server should have seen 1 request`) {
+			return
+		}
+	})
+	t.Run("POST with idempotency key is retried with the same body", func(t *testing.T) {
+		var count int32
+		srv := httptest.NewServer(failN(2, http.StatusServiceUnavailable, &count))
+		defer srv.Close()
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL, bytes.NewBufferString(`hello`))
+		if !assert.NoError(t, err, `http.NewRequest should succeed`) {
+			return
+		}
+		req.Header.Set("Idempotency-Key", "abc")
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(5))}
+		res, err := cl.Do(req)
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		body, _ := io.ReadAll(res.Body)
+		if !assert.Equal(t, `hello`, string(body), `body should have been rewound`) {
+			return
+		}
+		if !assert.Equal(t, int32(3), atomic.LoadInt32(&count), `server should have seen 3 requests`) {
+			return
+		}
+	})
+	t.Run("Retry-After in seconds", func(t *testing.T) {
+		var count int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&count, 1) == 1 {
+				w.Header().Set("Retry-After", strconv.Itoa(1))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(5))}
+		start := time.Now()
+		res, err := cl.Get(srv.URL)
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		if !assert.True(t, time.Since(start) >= time.Second, `should have waited for Retry-After`) {
+			return
+		}
+	})
+	t.Run("Retry-After as HTTP-date is truncated", func(t *testing.T) {
+		var count int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&count, 1) == 1 {
+				w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(5), backoffhttp.WithMaxRetryAfter(200*time.Millisecond))}
+		start := time.Now()
+		res, err := cl.Get(srv.URL)
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		elapsed := time.Since(start)
+		if !assert.True(t, elapsed >= 200*time.Millisecond && elapsed < 5*time.Second, `should have waited for the truncated Retry-After (%s)`, elapsed) {
+			return
+		}
+	})
+	t.Run("Connection errors", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if !assert.NoError(t, err, `net.Listen should succeed`) {
+			return
+		}
+
+		// accept and immediately close the first connections, then
+		// start serving
+		var count int32
+		go func() {
+			for i := 0; i < 2; i++ {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				atomic.AddInt32(&count, 1)
+				conn.Close()
+			}
+			_ = http.Serve(l, failN(0, http.StatusOK, &count))
+		}()
+		defer l.Close()
+
+		cl := &http.Client{Transport: backoffhttp.NewTransport(newPolicy(5), backoffhttp.WithTransport(&http.Transport{DisableKeepAlives: true}))}
+		res, err := cl.Get("http://" + l.Addr().String())
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		defer res.Body.Close()
+
+		if !assert.Equal(t, http.StatusOK, res.StatusCode, `final response should be 200`) {
+			return
+		}
+	})
+}
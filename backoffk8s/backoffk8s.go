@@ -0,0 +1,184 @@
+// Package backoffk8s converts between the backoff parameters used by
+// k8s.io/apimachinery/pkg/util/wait.Backoff and this library's policies.
+//
+// To avoid depending on Kubernetes, this package defines Backoff, a
+// struct with the same fields as wait.Backoff. Converting between the
+// two is a simple field-by-field copy:
+//
+//	b := backoffk8s.Backoff(waitBackoff)
+package backoffk8s
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// Backoff mirrors wait.Backoff from k8s.io/apimachinery
+type Backoff struct {
+	// Duration is the initial duration.
+	Duration time.Duration
+	// Factor is multiplied to the duration on each iteration.
+	// Values less than or equal to 1.0 keep the duration constant.
+	Factor float64
+	// Jitter specifies that each duration may be extended by a random
+	// amount of up to Jitter*duration.
+	Jitter float64
+	// Steps is the total number of attempts.
+	Steps int
+	// Cap is the maximum duration. 0 means that there is no maximum.
+	Cap time.Duration
+}
+
+// noCap is used as the maximum interval when Cap is not specified.
+// time.Duration(math.MaxInt64) can not be used, as the conversion
+// from float64 back to time.Duration would overflow.
+const noCap = time.Duration(1 << 62)
+
+// Defaults used by the backoff package when the corresponding
+// options are not specified
+const (
+	defaultMaxInterval = time.Minute
+	defaultMaxRetries  = 10
+	defaultMinInterval = 500 * time.Millisecond
+	defaultMultiplier  = 1.5
+)
+
+// ExponentialOptions converts the parameters into options for
+// backoff.NewExponentialPolicy:
+//
+//   - Duration is mapped to backoff.WithMinInterval
+//   - Factor is mapped to backoff.WithMultiplier
+//   - Jitter is mapped to backoff.WithJitterFactor and
+//     backoff.WithJitterStrategy(backoff.JitterPositive)
+//   - Steps is mapped to backoff.WithMaxRetries(Steps-1)
+//   - Cap is mapped to backoff.WithMaxInterval
+//
+// Parameters that can not be represented by ExponentialPolicy, namely
+// a Factor less than or equal to 1.0, or Steps less than 2 are not
+// mapped. Use Policy to handle these cases.
+//
+// Note that with wait.ExponentialBackoff, reaching Cap stops the
+// iteration, whereas the intervals simply stop growing in ExponentialPolicy.
+func ExponentialOptions(b Backoff) []backoff.ExponentialOption {
+	var options []backoff.ExponentialOption
+	if b.Duration > 0 {
+		options = append(options, backoff.WithMinInterval(b.Duration))
+	}
+	if b.Factor > 1 {
+		options = append(options, backoff.WithMultiplier(b.Factor))
+	}
+	if b.Jitter > 0 {
+		options = append(options,
+			backoff.WithJitterFactor(b.Jitter),
+			backoff.WithJitterStrategy(backoff.JitterPositive),
+		)
+	}
+	if b.Steps > 1 {
+		options = append(options, backoff.WithMaxRetries(b.Steps-1))
+	}
+	if b.Cap > 0 {
+		options = append(options, backoff.WithMaxInterval(b.Cap))
+	} else {
+		options = append(options, backoff.WithMaxInterval(noCap))
+	}
+	return options
+}
+
+// Policy creates the policy that best matches the parameters:
+// backoff.Null if Steps is less than 2, backoff.Constant if Factor is
+// less than or equal to 1.0, and backoff.Exponential otherwise.
+func Policy(b Backoff) backoff.Policy {
+	if b.Steps < 2 {
+		return backoff.Null()
+	}
+
+	if b.Factor <= 1 {
+		options := []backoff.Option{
+			backoff.WithInterval(b.Duration),
+			backoff.WithMaxRetries(b.Steps - 1),
+		}
+		if b.Jitter > 0 {
+			options = append(options,
+				backoff.WithJitterFactor(b.Jitter),
+				backoff.WithJitterStrategy(backoff.JitterPositive),
+			)
+		}
+		return backoff.Constant(options...)
+	}
+
+	return backoff.Exponential(ExponentialOptions(b)...)
+}
+
+// These are only used to compare identities of options
+var (
+	identJitterFactor   = backoff.WithJitterFactor(0).Ident()
+	identJitterStrategy = backoff.WithJitterStrategy(0).Ident()
+	identMaxInterval    = backoff.WithMaxInterval(0).Ident()
+	identMaxRetries     = backoff.WithMaxRetries(0).Ident()
+	identMinInterval    = backoff.WithMinInterval(0).Ident()
+	identMultiplier     = backoff.WithMultiplier(0).Ident()
+)
+
+// FromExponentialOptions converts options for backoff.NewExponentialPolicy
+// into the equivalent parameters. Options that are not specified take
+// the same default values as backoff.NewExponentialPolicy.
+//
+// Some values can not be represented exactly:
+//
+//   - Retrying forever (backoff.WithMaxRetries(0)) is mapped to
+//     math.MaxInt32 steps
+//   - backoff.JitterSymmetric jitter can not be expressed, so the jitter
+//     factor is doubled, so that the randomized durations span a range
+//     of the same width
+func FromExponentialOptions(options ...backoff.ExponentialOption) Backoff {
+	b := Backoff{
+		Duration: defaultMinInterval,
+		Factor:   defaultMultiplier,
+		Steps:    defaultMaxRetries + 1,
+		Cap:      defaultMaxInterval,
+	}
+
+	var jitterFactor float64
+	var jitterStrategy backoff.JitterStrategy
+	for _, option := range options {
+		switch option.Ident() {
+		case identJitterFactor:
+			jitterFactor = option.Value().(float64)
+		case identJitterStrategy:
+			jitterStrategy = option.Value().(backoff.JitterStrategy)
+		case identMaxInterval:
+			b.Cap = option.Value().(time.Duration)
+		case identMaxRetries:
+			retries := option.Value().(int)
+			if retries <= 0 {
+				b.Steps = 1<<31 - 1
+			} else {
+				b.Steps = retries + 1
+			}
+		case identMinInterval:
+			b.Duration = option.Value().(time.Duration)
+		case identMultiplier:
+			if v := option.Value().(float64); v > 1 {
+				b.Factor = v
+			}
+		}
+	}
+
+	switch jitterStrategy {
+	case backoff.JitterPositive:
+		if jitterFactor > 0 {
+			b.Jitter = jitterFactor
+		}
+	default:
+		// Values outside of this range disable jittering
+		if jitterFactor > 0 && jitterFactor < 1 {
+			b.Jitter = jitterFactor * 2
+		}
+	}
+
+	if b.Cap >= noCap {
+		b.Cap = 0
+	}
+	return b
+}
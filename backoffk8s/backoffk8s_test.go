@@ -0,0 +1,180 @@
+package backoffk8s_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffk8s"
+	"github.com/stretchr/testify/assert"
+)
+
+// step reproduces wait.Backoff.Step() without jitter
+func step(b *backoffk8s.Backoff) time.Duration {
+	d := b.Duration
+	if b.Factor != 0 {
+		b.Duration = time.Duration(float64(b.Duration) * b.Factor)
+		if b.Cap > 0 && b.Duration > b.Cap {
+			b.Duration = b.Cap
+		}
+	}
+	return d
+}
+
+func TestExponentialOptions(t *testing.T) {
+	b := backoffk8s.Backoff{
+		Duration: 10 * time.Millisecond,
+		Factor:   3,
+		Steps:    8,
+		Cap:      time.Second,
+	}
+
+	ig := backoff.NewExponentialInterval(backoffk8s.ExponentialOptions(b)...)
+	k := b
+	for i := 0; i < 10; i++ {
+		if !assert.Equal(t, step(&k), ig.Next(), `interval %d should match wait.Backoff`, i) {
+			return
+		}
+	}
+
+	t.Run("No cap", func(t *testing.T) {
+		b := backoffk8s.Backoff{Duration: time.Minute, Factor: 2, Steps: 3}
+		ig := backoff.NewExponentialInterval(backoffk8s.ExponentialOptions(b)...)
+		k := b
+		for i := 0; i < 10; i++ {
+			if !assert.Equal(t, step(&k), ig.Next(), `interval %d should match wait.Backoff`, i) {
+				return
+			}
+		}
+	})
+	t.Run("Jitter", func(t *testing.T) {
+		b := backoffk8s.Backoff{Duration: time.Second, Factor: 2, Jitter: 1.5, Steps: 3}
+		for i := 0; i < 100; i++ {
+			ig := backoff.NewExponentialInterval(backoffk8s.ExponentialOptions(b)...)
+			d := ig.Next()
+			if !assert.True(t, d >= time.Second && d <= 2500*time.Millisecond, `jittered interval (%s) should be in [1s, 2.5s]`, d) {
+				return
+			}
+		}
+	})
+	t.Run("Jitter does not compound", func(t *testing.T) {
+		const runs = 1000
+		b := backoffk8s.Backoff{Duration: 100 * time.Millisecond, Factor: 2, Jitter: 1, Steps: 8}
+		options := append(backoffk8s.ExponentialOptions(b), backoff.WithRNG(rand.New(rand.NewSource(1))))
+
+		var sums [7]time.Duration
+		for i := 0; i < runs; i++ {
+			ig := backoff.NewExponentialInterval(options...)
+			for j := range sums {
+				sums[j] += ig.Next()
+			}
+		}
+
+		// wait.Backoff jitters each interval without carrying the
+		// jitter over, so the mean of the n-th interval is that of the
+		// n-th interval without jitter, extended by Jitter/2
+		k := b
+		for j, sum := range sums {
+			expected := float64(step(&k)) * (1 + b.Jitter/2)
+			mean := float64(sum / runs)
+			if !assert.InEpsilon(t, expected, mean, 0.05, `mean of interval %d (%s) should match wait.Backoff (%s)`, j, time.Duration(mean), time.Duration(expected)) {
+				return
+			}
+		}
+	})
+}
+
+func TestPolicy(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Backoff  backoffk8s.Backoff
+		Expected interface{}
+		Attempts int
+	}{
+		{
+			Name:     "Single step",
+			Backoff:  backoffk8s.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 1},
+			Expected: &backoff.NullPolicy{},
+			Attempts: 1,
+		},
+		{
+			Name:     "Constant",
+			Backoff:  backoffk8s.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4},
+			Expected: &backoff.ConstantPolicy{},
+			Attempts: 4,
+		},
+		{
+			Name:     "Exponential",
+			Backoff:  backoffk8s.Backoff{Duration: time.Millisecond, Factor: 1.5, Jitter: 0.1, Steps: 5},
+			Expected: &backoff.ExponentialPolicy{},
+			Attempts: 5,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			p := backoffk8s.Policy(tc.Backoff)
+			if !assert.IsType(t, tc.Expected, p) {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var count int
+			c := p.Start(ctx)
+			for backoff.Continue(c) {
+				count++
+			}
+			if !assert.Equal(t, tc.Attempts, count, `number of attempts should match Steps`) {
+				return
+			}
+		})
+	}
+}
+
+func TestFromExponentialOptions(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		expected := backoffk8s.Backoff{
+			Duration: 500 * time.Millisecond,
+			Factor:   1.5,
+			Steps:    11,
+			Cap:      time.Minute,
+		}
+		if !assert.Equal(t, expected, backoffk8s.FromExponentialOptions()) {
+			return
+		}
+	})
+	t.Run("Round trip", func(t *testing.T) {
+		b := backoffk8s.Backoff{
+			Duration: 2 * time.Second,
+			Factor:   1.6,
+			Jitter:   0.2,
+			Steps:    7,
+			Cap:      2 * time.Minute,
+		}
+		if !assert.Equal(t, b, backoffk8s.FromExponentialOptions(backoffk8s.ExponentialOptions(b)...)) {
+			return
+		}
+
+		b.Cap = 0
+		if !assert.Equal(t, b, backoffk8s.FromExponentialOptions(backoffk8s.ExponentialOptions(b)...)) {
+			return
+		}
+	})
+	t.Run("Symmetric jitter and infinite retries", func(t *testing.T) {
+		b := backoffk8s.FromExponentialOptions(
+			backoff.WithJitterFactor(0.1),
+			backoff.WithMaxRetries(0),
+		)
+		if !assert.Equal(t, 0.2, b.Jitter, `symmetric jitter should be doubled`) {
+			return
+		}
+		if !assert.Equal(t, 1<<31-1, b.Steps, `retrying forever should be mapped to MaxInt32 steps`) {
+			return
+		}
+	})
+}
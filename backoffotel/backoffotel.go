@@ -0,0 +1,190 @@
+// Package backoffotel records backoff controllers as OpenTelemetry spans.
+//
+// Each controller started from a policy wrapped by NewPolicy is recorded
+// as a span, with an event for each attempt and for each scheduled
+// retry. Errors reported via backoff.Failure are recorded as well:
+//
+//	ctx, cancel := context.WithCancel(ctx)
+//	defer cancel() // ends the span
+//
+//	p := backoffotel.NewPolicy(backoff.Exponential(), tracer)
+//	c := p.Start(ctx)
+//	for backoff.Continue(c) {
+//		if err := op(); err != nil {
+//			backoff.Failure(c, err)
+//			continue
+//		}
+//		backoff.Success(c)
+//		break
+//	}
+package backoffotel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys used in spans and events
+const (
+	AttemptKey  = attribute.Key("backoff.attempt")
+	AttemptsKey = attribute.Key("backoff.attempts")
+	DelayKey    = attribute.Key("backoff.delay_ms")
+	GaveUpKey   = attribute.Key("backoff.gave_up")
+)
+
+// Event names
+const (
+	AttemptEvent = "backoff.attempt"
+	WaitEvent    = "backoff.wait"
+)
+
+const defaultSpanName = "backoff"
+
+// stopGracePeriod is how long the span is kept open after the context is
+// done, waiting for the controller to stop
+const stopGracePeriod = 100 * time.Millisecond
+
+// Policy wraps a backoff.Policy, recording each controller as a span
+type Policy struct {
+	policy   backoff.Policy
+	spanName string
+	tracer   trace.Tracer
+}
+
+// NewPolicy wraps the given policy so that each controller is recorded
+// as a span created by `tracer`. The span is a child of the span in the
+// context passed to Start.
+//
+// The span ends once the context passed to Start is done, and not
+// when the controller stops, so that the error from the final attempt
+// can still be recorded. As with any controller, cancel the context
+// after the operation has been performed.
+//
+// The events and attributes are recorded by notifying observers attached
+// to the context via backoff.ContextWithObserver, which all policies in
+// the backoff package (and those built with backoff.NewController) do.
+// If the wrapped policy does not, the span is still ended shortly after
+// the context is done, but without any events.
+func NewPolicy(policy backoff.Policy, tracer trace.Tracer, options ...Option) *Policy {
+	spanName := defaultSpanName
+	for _, option := range options {
+		switch option.Ident() {
+		case identSpanName{}:
+			spanName = option.Value().(string)
+		}
+	}
+	return &Policy{
+		policy:   policy,
+		spanName: spanName,
+		tracer:   tracer,
+	}
+}
+
+func (p *Policy) Start(ctx context.Context) backoff.Controller {
+	ctx, span := p.tracer.Start(ctx, p.spanName)
+	o := &observer{
+		span:    span,
+		stopped: make(chan struct{}),
+	}
+	context.AfterFunc(ctx, func() {
+		// give the controller a chance to notice, so that the span
+		// reflects its final state. Controllers that do not notify
+		// observers never stop, so do not wait for them forever
+		timer := time.NewTimer(stopGracePeriod)
+		defer timer.Stop()
+		select {
+		case <-o.stopped:
+		case <-timer.C:
+		}
+		o.end()
+	})
+	return &controller{
+		Controller: p.policy.Start(backoff.ContextWithObserver(ctx, o)),
+	}
+}
+
+// controller is the Controller returned by Policy.Start. It forwards
+// the outcome of the attempts to the wrapped controller, which notifies
+// the observer
+type controller struct {
+	backoff.Controller
+}
+
+// Success forwards the outcome to the wrapped controller.
+// See backoff.FeedbackController
+func (c *controller) Success() {
+	backoff.Success(c.Controller)
+}
+
+// Failure forwards the outcome to the wrapped controller.
+// See backoff.FeedbackController
+func (c *controller) Failure(err error) {
+	backoff.Failure(c.Controller, err)
+}
+
+// RecordError reports the failure of the latest attempt via
+// backoff.Failure, which records `err` in the span of the controller.
+// It does nothing if `err` is nil.
+func RecordError(c backoff.Controller, err error) {
+	if err == nil {
+		return
+	}
+	backoff.Failure(c, err)
+}
+
+type observer struct {
+	attempts int64
+	gaveUp   int32
+	once     sync.Once
+	span     trace.Span
+	stopped  chan struct{}
+}
+
+func (o *observer) OnAttempt(n int) {
+	atomic.StoreInt64(&o.attempts, int64(n))
+	o.span.AddEvent(AttemptEvent, trace.WithAttributes(AttemptKey.Int(n)))
+}
+
+func (o *observer) OnWait(n int, d time.Duration) {
+	o.span.AddEvent(WaitEvent, trace.WithAttributes(
+		AttemptKey.Int(n),
+		DelayKey.Int64(d.Milliseconds()),
+	))
+}
+
+// OnFeedback records the error of a failed attempt.
+// See backoff.FeedbackObserver
+func (o *observer) OnFeedback(n int, f backoff.Feedback) {
+	if f.Success || f.Err == nil {
+		return
+	}
+	o.span.RecordError(f.Err, trace.WithAttributes(AttemptKey.Int(n)))
+}
+
+func (o *observer) OnGiveUp(reason error) {
+	atomic.StoreInt32(&o.gaveUp, 1)
+	o.span.SetStatus(codes.Error, reason.Error())
+}
+
+func (o *observer) OnStop() {
+	close(o.stopped)
+}
+
+// end sets the attributes from whatever has been observed so far, and
+// ends the span
+func (o *observer) end() {
+	o.once.Do(func() {
+		o.span.SetAttributes(
+			AttemptsKey.Int64(atomic.LoadInt64(&o.attempts)),
+			GaveUpKey.Bool(atomic.LoadInt32(&o.gaveUp) == 1),
+		)
+		o.span.End()
+	})
+}
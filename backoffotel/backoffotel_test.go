@@ -0,0 +1,163 @@
+package backoffotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffotel"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPolicy(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+
+	p := backoffotel.NewPolicy(
+		backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(2),
+		),
+		tp.Tracer("test"),
+		backoffotel.WithSpanName("fetch"),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	c := p.Start(ctx)
+	for backoff.Continue(c) {
+		backoff.Failure(c, errors.New("boom"))
+	}
+	cancel()
+
+	// span is ended asynchronously after the context is canceled
+	deadline := time.Now().Add(time.Second)
+	for len(exporter.GetSpans()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	spans := exporter.GetSpans()
+	if !assert.Len(t, spans, 1, `one span should be recorded`) {
+		return
+	}
+	span := spans[0]
+	if !assert.Equal(t, "fetch", span.Name, `span name should match`) {
+		return
+	}
+	if !assert.Equal(t, codes.Error, span.Status.Code, `span status should be error`) {
+		return
+	}
+	if !assert.Equal(t, backoff.ErrMaxRetries.Error(), span.Status.Description, `status description should be the reason`) {
+		return
+	}
+
+	var attempts, waits int
+	var exceptions []int64
+	for _, ev := range span.Events {
+		switch ev.Name {
+		case backoffotel.AttemptEvent:
+			attempts++
+		case backoffotel.WaitEvent:
+			waits++
+		case "exception":
+			for _, attr := range ev.Attributes {
+				if attr.Key == backoffotel.AttemptKey {
+					exceptions = append(exceptions, attr.Value.AsInt64())
+				}
+			}
+		}
+	}
+	if !assert.Equal(t, 3, attempts, `attempt events`) {
+		return
+	}
+	if !assert.Equal(t, []int64{1, 2, 3}, exceptions, `errors should be recorded with their attempts`) {
+		return
+	}
+	if !assert.True(t, waits >= 2, `wait events`) {
+		return
+	}
+
+	var found bool
+	for _, attr := range span.Attributes {
+		if attr.Key == backoffotel.AttemptsKey {
+			found = true
+			if !assert.Equal(t, int64(3), attr.Value.AsInt64(), `attempts attribute`) {
+				return
+			}
+		}
+	}
+	if !assert.True(t, found, `attempts attribute should be set`) {
+		return
+	}
+}
+
+func TestRecordErrorForeignController(t *testing.T) {
+	c := backoff.Null().Start(context.Background())
+	// should not panic
+	backoffotel.RecordError(c, errors.New("boom"))
+}
+
+func TestFeedback(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	adaptive := backoff.NewAdaptivePolicy(backoff.WithMinInterval(time.Second))
+	p := backoffotel.NewPolicy(adaptive, tp.Tracer("test"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := p.Start(ctx)
+	if !assert.Implements(t, (*backoff.FeedbackController)(nil), c, `controller should accept feedback`) {
+		return
+	}
+	if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+		return
+	}
+
+	backoff.Failure(c, nil)
+	if !assert.Equal(t, 2*time.Second, adaptive.Interval(), `failure should be forwarded`) {
+		return
+	}
+	backoff.Success(c)
+	if !assert.Equal(t, time.Second, adaptive.Interval(), `success should be forwarded`) {
+		return
+	}
+}
+
+func TestPolicyWithoutObservers(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+
+	// ScriptedController ignores the observers attached to the context,
+	// so it never reports that it has stopped
+	sc := backofftest.NewScriptedController()
+	p := backoffotel.NewPolicy(sc, tp.Tracer("test"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := p.Start(ctx)
+	go sc.Tick()
+	if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+		return
+	}
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for len(exporter.GetSpans()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !assert.Len(t, exporter.GetSpans(), 1, `span should be ended after the context is done`) {
+		return
+	}
+}
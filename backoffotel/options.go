@@ -0,0 +1,13 @@
+package backoffotel
+
+import "github.com/lestrrat-go/option"
+
+type identSpanName struct{}
+
+// Option is an option that can be passed to NewPolicy
+type Option = option.Interface
+
+// WithSpanName specifies the name of the spans. The default is "backoff"
+func WithSpanName(v string) Option {
+	return option.New(identSpanName{}, v)
+}
@@ -0,0 +1,149 @@
+//go:build go1.21
+
+// Package backoffslog logs the events of backoff controllers via log/slog.
+//
+//	p := backoff.Exponential(
+//		backoffslog.WithLogger(logger, backoffslog.WithName("fetch_user")),
+//	)
+//
+// To log the cause of each retry, report the error of the failed attempt
+// via backoff.Failure before asking for the next attempt:
+//
+//	for backoff.Continue(c) {
+//		if err := op(); err != nil {
+//			backoff.Failure(c, err) // logged as the "error" attribute
+//			continue
+//		}
+//		backoff.Success(c)
+//		break
+//	}
+package backoffslog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+type config struct {
+	attemptLevel slog.Level
+	giveUpLevel  slog.Level
+	name         string
+	retryLevel   slog.Level
+	stopLevel    slog.Level
+}
+
+// WithLogger returns an option that, when passed to a policy constructor,
+// logs the events of every controller created by that policy:
+//
+//   - each attempt (attempt number)
+//   - each scheduled retry (attempt number, interval, and the error of
+//     the failed attempt)
+//   - giving up (attempt number, elapsed time, reason, and the error of
+//     the last attempt)
+//   - stopping (attempt number, elapsed time, and whether it succeeded
+//     or gave up)
+//
+// Retries are only logged when the user asks for another attempt, and
+// giving up only when the user asks for one more attempt than allowed,
+// so successful calls are never logged above slog.LevelDebug.
+func WithLogger(logger *slog.Logger, options ...Option) backoff.ControllerOption {
+	cfg := config{
+		attemptLevel: slog.LevelDebug,
+		giveUpLevel:  slog.LevelWarn,
+		retryLevel:   slog.LevelInfo,
+		stopLevel:    slog.LevelDebug,
+	}
+	for _, option := range options {
+		switch option.Ident() {
+		case identAttemptLevel{}:
+			cfg.attemptLevel = option.Value().(slog.Level)
+		case identGiveUpLevel{}:
+			cfg.giveUpLevel = option.Value().(slog.Level)
+		case identName{}:
+			cfg.name = option.Value().(string)
+		case identRetryLevel{}:
+			cfg.retryLevel = option.Value().(slog.Level)
+		case identStopLevel{}:
+			cfg.stopLevel = option.Value().(slog.Level)
+		}
+	}
+
+	if cfg.name != "" {
+		logger = logger.With(slog.String("policy", cfg.name))
+	}
+
+	return backoff.WithObserverFactory(func() backoff.Observer {
+		return &observer{
+			cfg:    &cfg,
+			logger: logger,
+			start:  time.Now(),
+		}
+	})
+}
+
+// observer is created for each controller
+type observer struct {
+	attempts  int
+	cfg       *config
+	err       error // the error of the latest attempt
+	gaveUp    bool
+	logger    *slog.Logger
+	start     time.Time
+	succeeded bool
+}
+
+func (o *observer) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	o.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+func (o *observer) OnAttempt(n int) {
+	o.attempts = n
+	o.err = nil
+	o.log(o.cfg.attemptLevel, "backoff attempt",
+		slog.Int("attempt", n),
+	)
+}
+
+func (o *observer) OnWait(n int, d time.Duration) {
+	attrs := []slog.Attr{
+		slog.Int("attempt", n),
+		slog.Duration("interval", d),
+	}
+	if o.err != nil {
+		attrs = append(attrs, slog.Any("error", o.err))
+	}
+	o.log(o.cfg.retryLevel, "backoff retry scheduled", attrs...)
+}
+
+func (o *observer) OnFeedback(_ int, f backoff.Feedback) {
+	if f.Success {
+		o.succeeded = true
+		return
+	}
+	o.err = f.Err
+}
+
+func (o *observer) OnGiveUp(reason error) {
+	o.gaveUp = true
+	attrs := []slog.Attr{
+		slog.Int("attempts", o.attempts),
+		slog.Duration("elapsed", time.Since(o.start)),
+		slog.Any("reason", reason),
+	}
+	if o.err != nil {
+		attrs = append(attrs, slog.Any("error", o.err))
+	}
+	o.log(o.cfg.giveUpLevel, "backoff gave up", attrs...)
+}
+
+func (o *observer) OnStop() {
+	o.log(o.cfg.stopLevel, "backoff stopped",
+		slog.Int("attempts", o.attempts),
+		slog.Duration("elapsed", time.Since(o.start)),
+		slog.Bool("succeeded", o.succeeded),
+		slog.Bool("gave_up", o.gaveUp),
+	)
+}
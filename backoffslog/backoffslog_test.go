@@ -0,0 +1,160 @@
+//go:build go1.21
+
+package backoffslog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffslog"
+	"github.com/stretchr/testify/assert"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) records(t *testing.T) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var r map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("failed to parse log record %q: %s", line, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	p := backoff.Constant(
+		backoff.WithInterval(time.Millisecond),
+		backoff.WithMaxRetries(2),
+		backoffslog.WithLogger(logger,
+			backoffslog.WithName("fetch_user"),
+			backoffslog.WithGiveUpLevel(slog.LevelError),
+		),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := p.Start(ctx)
+	var n int
+	for backoff.Continue(c) {
+		n++
+		backoff.Failure(c, fmt.Errorf("attempt %d failed", n))
+	}
+
+	var records []map[string]interface{}
+	if !assert.Eventually(t, func() bool {
+		records = buf.records(t)
+		return len(records) > 0 && records[len(records)-1]["msg"] == "backoff stopped"
+	}, 5*time.Second, 10*time.Millisecond, `stop should be logged`) {
+		return
+	}
+
+	var messages []string
+	for _, r := range records {
+		if !assert.Equal(t, "fetch_user", r["policy"], `policy name should be logged`) {
+			return
+		}
+		messages = append(messages, r["msg"].(string))
+	}
+	expected := []string{
+		"backoff attempt",
+		"backoff retry scheduled",
+		"backoff attempt",
+		"backoff retry scheduled",
+		"backoff attempt",
+		"backoff gave up",
+		"backoff stopped",
+	}
+	if !assert.Equal(t, expected, messages, `events should be logged in order`) {
+		return
+	}
+
+	retry := records[1]
+	if !assert.Equal(t, float64(2), retry["attempt"], `attempt number should be logged`) {
+		return
+	}
+	if !assert.Equal(t, float64(time.Millisecond), retry["interval"], `interval should be logged`) {
+		return
+	}
+	if !assert.Equal(t, "attempt 1 failed", retry["error"], `error cause should be logged`) {
+		return
+	}
+
+	giveUp := records[5]
+	if !assert.Equal(t, "ERROR", giveUp["level"], `level should be configurable`) {
+		return
+	}
+	if !assert.Equal(t, backoff.ErrMaxRetries.Error(), giveUp["reason"], `reason should be logged`) {
+		return
+	}
+	if !assert.Equal(t, "attempt 3 failed", giveUp["error"], `error of the last attempt should be logged`) {
+		return
+	}
+	if !assert.Equal(t, true, records[6]["gave_up"], `outcome should be logged`) {
+		return
+	}
+}
+
+func TestWithLoggerSuccess(t *testing.T) {
+	for _, newPolicy := range []func(backoff.ControllerOption) backoff.Policy{
+		func(option backoff.ControllerOption) backoff.Policy { return backoff.Null(option) },
+		func(option backoff.ControllerOption) backoff.Policy { return backoff.Exponential(option) },
+	} {
+		var buf syncBuffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		p := newPolicy(backoffslog.WithLogger(logger))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+			backoff.Success(c)
+			break
+		}
+		cancel()
+
+		var records []map[string]interface{}
+		if !assert.Eventually(t, func() bool {
+			records = buf.records(t)
+			return len(records) > 0 && records[len(records)-1]["msg"] == "backoff stopped"
+		}, 5*time.Second, 10*time.Millisecond, `%T: stop should be logged`, p) {
+			return
+		}
+		for _, r := range records {
+			if !assert.Equal(t, "DEBUG", r["level"], `%T: %q should not be logged above debug`, p, r["msg"]) {
+				return
+			}
+		}
+		if !assert.Equal(t, true, records[len(records)-1]["succeeded"], `%T: success should be logged`, p) {
+			return
+		}
+	}
+}
@@ -0,0 +1,48 @@
+//go:build go1.21
+
+package backoffslog
+
+import (
+	"log/slog"
+
+	"github.com/lestrrat-go/option"
+)
+
+type identAttemptLevel struct{}
+type identGiveUpLevel struct{}
+type identName struct{}
+type identRetryLevel struct{}
+type identStopLevel struct{}
+
+// Option is an option that can be passed to WithLogger
+type Option = option.Interface
+
+// WithName specifies the name of the policy, which is included in
+// every log record as the "policy" attribute.
+func WithName(v string) Option {
+	return option.New(identName{}, v)
+}
+
+// WithAttemptLevel specifies the level used to log each attempt.
+// The default is slog.LevelDebug.
+func WithAttemptLevel(v slog.Level) Option {
+	return option.New(identAttemptLevel{}, v)
+}
+
+// WithRetryLevel specifies the level used to log each scheduled retry.
+// The default is slog.LevelInfo.
+func WithRetryLevel(v slog.Level) Option {
+	return option.New(identRetryLevel{}, v)
+}
+
+// WithGiveUpLevel specifies the level used to log that the controller
+// has given up. The default is slog.LevelWarn.
+func WithGiveUpLevel(v slog.Level) Option {
+	return option.New(identGiveUpLevel{}, v)
+}
+
+// WithStopLevel specifies the level used to log that the controller
+// has stopped. The default is slog.LevelDebug.
+func WithStopLevel(v slog.Level) Option {
+	return option.New(identStopLevel{}, v)
+}
@@ -0,0 +1,95 @@
+// Package backoffsql retries database/sql transactions that fail with
+// transient errors, such as serialization failures and deadlocks.
+package backoffsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// RetryTx runs `fn` within a transaction, and commits it. If `fn`, or
+// beginning the transaction fails with an error that the classifier
+// considers retryable, the transaction is rolled back and the whole
+// transaction is run again according to the policy.
+//
+// Errors from committing the transaction are treated differently: if
+// the connection is lost during the commit, the transaction may or may
+// not have been applied, and running it again could apply it twice.
+// Such errors are only retried if the commit classifier considers them
+// retryable, which by default only SerializationClassifier does, as the
+// database explicitly reports that the transaction was rolled back.
+// See WithCommitClassifier.
+//
+// Because the transaction may be run multiple times, `fn` should not
+// have side effects outside of the transaction.
+//
+// The error from the last attempt is returned.
+func RetryTx(ctx context.Context, db *sql.DB, policy backoff.Policy, fn func(*sql.Tx) error, options ...Option) error {
+	classifier := DefaultClassifier
+	commitClassifier := SerializationClassifier
+	var txOptions *sql.TxOptions
+	for _, option := range options {
+		switch option.Ident() {
+		case identClassifier{}:
+			classifier = option.Value().(Classifier)
+		case identCommitClassifier{}:
+			commitClassifier = option.Value().(Classifier)
+		case identTxOptions{}:
+			txOptions = option.Value().(*sql.TxOptions)
+		}
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var err error
+	c := policy.Start(cctx)
+	for backoff.Continue(c) {
+		var commit bool
+		commit, err = runTx(ctx, db, txOptions, fn)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		retryable := classifier
+		if commit {
+			retryable = commitClassifier
+		}
+		if !retryable.Retryable(err) {
+			return err
+		}
+	}
+
+	if err == nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf(`backoffsql: backoff ended before any attempt was made`)
+	}
+	return err
+}
+
+// runTx runs a single transaction. `commit` reports whether the error
+// was returned by tx.Commit()
+func runTx(ctx context.Context, db *sql.DB, txOptions *sql.TxOptions, fn func(*sql.Tx) error) (commit bool, err error) {
+	tx, err := db.BeginTx(ctx, txOptions)
+	if err != nil {
+		return false, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,282 @@
+package backoffsql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffsql"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver is a minimal database/sql/driver implementation. Each call
+// to Exec returns the next error in `errors`, and each call to Commit
+// the next error in `commitErrors` (nil once exhausted)
+type fakeDriver struct {
+	mu           sync.Mutex
+	errors       []error
+	commitErrors []error
+	begins       int
+	commits      int
+	rollbacks    int
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                        { return d }
+func (d *fakeDriver) Open(string) (driver.Conn, error)             { return &fakeConn{d: d}, nil }
+
+func (d *fakeDriver) counts() (int, int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.begins, d.commits, d.rollbacks
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New(`not implemented`) }
+func (c *fakeConn) Close() error                        { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.begins++
+	return &fakeTx{d: c.d}, nil
+}
+
+func (c *fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	if len(c.d.errors) == 0 {
+		return driver.RowsAffected(1), nil
+	}
+	err := c.d.errors[0]
+	c.d.errors = c.d.errors[1:]
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.commits++
+	if len(tx.d.commitErrors) == 0 {
+		return nil
+	}
+	err := tx.d.commitErrors[0]
+	tx.d.commitErrors = tx.d.commitErrors[1:]
+	return err
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.rollbacks++
+	return nil
+}
+
+// pgError mimics the errors returned by PostgreSQL drivers
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string    { return `pq: ` + e.code }
+func (e *pgError) SQLState() string { return e.code }
+
+// mysqlError mimics the errors returned by github.com/go-sql-driver/mysql
+type mysqlError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string { return fmt.Sprintf(`Error %d: %s`, e.Number, e.Message) }
+
+func newPolicy() backoff.Policy {
+	return backoff.Constant(
+		backoff.WithInterval(time.Millisecond),
+		backoff.WithMaxRetries(5),
+	)
+}
+
+func TestRetryTx(t *testing.T) {
+	exec := func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE accounts SET balance = balance - 1`)
+		return err
+	}
+
+	t.Run("Retry transient errors", func(t *testing.T) {
+		d := &fakeDriver{errors: []error{
+			&pgError{code: "40001"},
+			fmt.Errorf(`wrapped: %w`, &pgError{code: "40P01"}),
+			&mysqlError{Number: 1213, Message: `Deadlock found when trying to get lock`},
+			driver.ErrBadConn,
+		}}
+		db := sql.OpenDB(d)
+		defer db.Close()
+
+		if !assert.NoError(t, backoffsql.RetryTx(context.Background(), db, newPolicy(), exec), `RetryTx should succeed`) {
+			return
+		}
+
+		begins, commits, rollbacks := d.counts()
+		if !assert.Equal(t, 5, begins, `transaction should have begun 5 times`) {
+			return
+		}
+		if !assert.Equal(t, 1, commits, `transaction should have been committed once`) {
+			return
+		}
+		if !assert.Equal(t, 4, rollbacks, `failed transactions should have been rolled back`) {
+			return
+		}
+	})
+	t.Run("Permanent errors", func(t *testing.T) {
+		d := &fakeDriver{errors: []error{&pgError{code: "23505"}}}
+		db := sql.OpenDB(d)
+		defer db.Close()
+
+		err := backoffsql.RetryTx(context.Background(), db, newPolicy(), exec)
+		var pgerr *pgError
+		if !assert.True(t, errors.As(err, &pgerr), `error should be returned as is`) {
+			return
+		}
+		begins, commits, rollbacks := d.counts()
+		if !assert.Equal(t, []int{1, 0, 1}, []int{begins, commits, rollbacks}) {
+			return
+		}
+	})
+	t.Run("Retries exhausted", func(t *testing.T) {
+		d := &fakeDriver{}
+		for i := 0; i < 10; i++ {
+			d.errors = append(d.errors, &pgError{code: "40001"})
+		}
+		db := sql.OpenDB(d)
+		defer db.Close()
+
+		err := backoffsql.RetryTx(context.Background(), db, newPolicy(), exec)
+		if !assert.Error(t, err, `RetryTx should fail`) {
+			return
+		}
+		begins, commits, _ := d.counts()
+		if !assert.Equal(t, []int{6, 0}, []int{begins, commits}, `initial + 5 retries, no commits`) {
+			return
+		}
+	})
+	t.Run("Commit errors", func(t *testing.T) {
+		// The commit may have been applied before the connection was
+		// lost, so the transaction must not be run again
+		d := &fakeDriver{commitErrors: []error{driver.ErrBadConn}}
+		db := sql.OpenDB(d)
+		defer db.Close()
+
+		err := backoffsql.RetryTx(context.Background(), db, newPolicy(), exec)
+		if !assert.Error(t, err, `RetryTx should fail`) {
+			return
+		}
+		begins, commits, _ := d.counts()
+		if !assert.Equal(t, []int{1, 1}, []int{begins, commits}, `transaction should not be retried`) {
+			return
+		}
+
+		// Serialization failures are reported when the transaction has
+		// been rolled back, so it is safe to retry them
+		d = &fakeDriver{commitErrors: []error{&pgError{code: "40001"}, &pgError{code: "40P01"}}}
+		db2 := sql.OpenDB(d)
+		defer db2.Close()
+
+		if !assert.NoError(t, backoffsql.RetryTx(context.Background(), db2, newPolicy(), exec), `RetryTx should succeed`) {
+			return
+		}
+		begins, commits, _ = d.counts()
+		if !assert.Equal(t, []int{3, 3}, []int{begins, commits}, `transaction should be retried twice`) {
+			return
+		}
+
+		// Connection errors from commits may be retried explicitly
+		d = &fakeDriver{commitErrors: []error{driver.ErrBadConn}}
+		db3 := sql.OpenDB(d)
+		defer db3.Close()
+
+		if !assert.NoError(t, backoffsql.RetryTx(context.Background(), db3, newPolicy(), exec, backoffsql.WithCommitClassifier(backoffsql.DefaultClassifier)), `RetryTx should succeed`) {
+			return
+		}
+	})
+	t.Run("Custom classifier", func(t *testing.T) {
+		errCustom := errors.New(`custom`)
+		d := &fakeDriver{errors: []error{errCustom}}
+		db := sql.OpenDB(d)
+		defer db.Close()
+
+		classifier := backoffsql.ClassifierFunc(func(err error) bool {
+			return errors.Is(err, errCustom)
+		})
+		if !assert.NoError(t, backoffsql.RetryTx(context.Background(), db, newPolicy(), exec, backoffsql.WithClassifier(classifier)), `RetryTx should succeed`) {
+			return
+		}
+	})
+	t.Run("Panic rolls back", func(t *testing.T) {
+		d := &fakeDriver{}
+		db := sql.OpenDB(d)
+		defer db.Close()
+
+		assert.Panics(t, func() {
+			_ = backoffsql.RetryTx(context.Background(), db, newPolicy(), func(*sql.Tx) error {
+				panic(`boom`)
+			})
+		})
+		_, _, rollbacks := d.counts()
+		if !assert.Equal(t, 1, rollbacks, `transaction should have been rolled back`) {
+			return
+		}
+	})
+}
+
+func TestClassifiers(t *testing.T) {
+	testcases := []struct {
+		Error     error
+		Retryable bool
+	}{
+		{Error: &pgError{code: "40001"}, Retryable: true},
+		{Error: &pgError{code: "40P01"}, Retryable: true},
+		{Error: &pgError{code: "08006"}, Retryable: true},
+		{Error: &pgError{code: "23505"}, Retryable: false},
+		{Error: &mysqlError{Number: 1205}, Retryable: true},
+		{Error: &mysqlError{Number: 1213}, Retryable: true},
+		{Error: &mysqlError{Number: 1062}, Retryable: false},
+		{Error: driver.ErrBadConn, Retryable: true},
+		{Error: sql.ErrConnDone, Retryable: true},
+		{Error: sql.ErrNoRows, Retryable: false},
+		{Error: context.Canceled, Retryable: false},
+		{Error: errors.New(`random`), Retryable: false},
+	}
+	for _, tc := range testcases {
+		if !assert.Equal(t, tc.Retryable, backoffsql.DefaultClassifier.Retryable(tc.Error), `classification of %q`, tc.Error) {
+			return
+		}
+	}
+
+	for err, retryable := range map[error]bool{
+		&pgError{code: "40001"}:   true,
+		&pgError{code: "40P01"}:   true,
+		&pgError{code: "08006"}:   false,
+		&mysqlError{Number: 1213}: false,
+		driver.ErrBadConn:         false,
+		errors.New(`random`):      false,
+	} {
+		if !assert.Equal(t, retryable, backoffsql.SerializationClassifier.Retryable(err), `serialization classification of %q`, err) {
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package backoffsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+)
+
+// Classifier decides whether an error returned from a transaction
+// is transient, i.e. whether the whole transaction may be re-run.
+type Classifier interface {
+	Retryable(error) bool
+}
+
+// ClassifierFunc is a function that implements the Classifier interface
+type ClassifierFunc func(error) bool
+
+func (f ClassifierFunc) Retryable(err error) bool {
+	return f(err)
+}
+
+// Any returns a Classifier that considers an error retryable if any of
+// the given classifiers do.
+func Any(classifiers ...Classifier) Classifier {
+	return ClassifierFunc(func(err error) bool {
+		for _, c := range classifiers {
+			if c.Retryable(err) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// DefaultClassifier is the classifier used by RetryTx unless one is
+// specified via WithClassifier. It is not used for errors returned when
+// committing a transaction. See WithCommitClassifier.
+var DefaultClassifier = Any(SQLStateClassifier, MySQLClassifier, ConnectionClassifier)
+
+// SQLStateClassifier recognizes errors that provide a `SQLState() string`
+// method, as errors from github.com/jackc/pgx and github.com/lib/pq do.
+// serialization_failure (40001), deadlock_detected (40P01), and
+// connection exceptions (class 08) are considered retryable.
+var SQLStateClassifier Classifier = ClassifierFunc(func(err error) bool {
+	var e interface{ SQLState() string }
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	state := e.SQLState()
+	switch {
+	case state == "40001", state == "40P01":
+		return true
+	case strings.HasPrefix(state, "08"):
+		return true
+	}
+	return false
+})
+
+// SerializationClassifier recognizes errors that provide a
+// `SQLState() string` method and report that the transaction has been
+// rolled back by the database: serialization_failure (40001) and
+// deadlock_detected (40P01). It is the default classifier for errors
+// returned when committing a transaction.
+var SerializationClassifier Classifier = ClassifierFunc(func(err error) bool {
+	var e interface{ SQLState() string }
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	switch e.SQLState() {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+})
+
+// MySQL error numbers that are considered retryable
+const (
+	mysqlLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+	mysqlLockDeadlock    = 1213 // ER_LOCK_DEADLOCK
+)
+
+// MySQLClassifier recognizes errors from github.com/go-sql-driver/mysql.
+// Deadlocks (1213) and lock wait timeouts (1205) are considered retryable.
+//
+// In order to avoid depending on the driver, the error number is read
+// from the `Number` field of the error via reflection.
+var MySQLClassifier Classifier = ClassifierFunc(func(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		rv := reflect.ValueOf(err)
+		if rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct {
+			continue
+		}
+		f := rv.FieldByName("Number")
+		if f.Kind() != reflect.Uint16 {
+			continue
+		}
+		switch f.Uint() {
+		case mysqlLockWaitTimeout, mysqlLockDeadlock:
+			return true
+		}
+		return false
+	}
+	return false
+})
+
+// ConnectionClassifier recognizes errors caused by dropped connections:
+// driver.ErrBadConn, sql.ErrConnDone, unexpected EOFs, and network errors.
+var ConnectionClassifier Classifier = ClassifierFunc(func(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var nerr net.Error
+	return errors.As(err, &nerr)
+})
@@ -0,0 +1,33 @@
+package backoffsql
+
+import (
+	"database/sql"
+
+	"github.com/lestrrat-go/option"
+)
+
+type identClassifier struct{}
+type identCommitClassifier struct{}
+type identTxOptions struct{}
+
+// Option is an option that can be passed to RetryTx
+type Option = option.Interface
+
+// WithClassifier specifies the Classifier used to decide if a failed
+// transaction should be retried. By default DefaultClassifier is used.
+func WithClassifier(v Classifier) Option {
+	return option.New(identClassifier{}, v)
+}
+
+// WithCommitClassifier specifies the Classifier used to decide if a
+// transaction whose commit failed should be retried. By default
+// SerializationClassifier is used, so that a transaction is not run
+// again when it is unknown whether the commit has been applied.
+func WithCommitClassifier(v Classifier) Option {
+	return option.New(identCommitClassifier{}, v)
+}
+
+// WithTxOptions specifies the options used to begin each transaction
+func WithTxOptions(v *sql.TxOptions) Option {
+	return option.New(identTxOptions{}, v)
+}
@@ -0,0 +1,158 @@
+package backofftest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// FakeClock is a backoff.Clock whose time only moves forward when
+// Advance is called. Use it with backoff.WithClock:
+//
+//	clock := backofftest.NewFakeClock(time.Time{})
+//	p := backoff.Exponential(backoff.WithClock(clock))
+//	c := p.Start(ctx)
+//	<-c.Next() // the first event is fired immediately
+//	clock.BlockUntil(1)
+//	clock.Advance(500 * time.Millisecond)
+//	<-c.Next()
+type FakeClock struct {
+	cond   *sync.Cond
+	mu     *sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock creates a new FakeClock set to `now`. If `now` is the
+// zero value, an arbitrary fixed time is used.
+func NewFakeClock(now time.Time) *FakeClock {
+	if now.IsZero() {
+		now = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	mu := &sync.Mutex{}
+	return &FakeClock{
+		cond: sync.NewCond(mu),
+		mu:   mu,
+		now:  now,
+	}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) backoff.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{
+		ch:    make(chan time.Time, 1),
+		clock: c,
+	}
+	c.timers = append(c.timers, t)
+	t.reset(d)
+	return t
+}
+
+// Advance moves the clock forward by `d`, firing the timers that expire
+// in the meantime in the order of their deadlines.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var expired []*fakeTimer
+	for _, t := range c.timers {
+		if t.active && !t.deadline.After(c.now) {
+			expired = append(expired, t)
+		}
+	}
+	sort.SliceStable(expired, func(i, j int) bool {
+		return expired[i].deadline.Before(expired[j].deadline)
+	})
+	for _, t := range expired {
+		t.fire()
+	}
+	c.cond.Broadcast()
+}
+
+// Timers returns the number of timers that are waiting to be fired.
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending()
+}
+
+// BlockUntil blocks until at least `n` timers are waiting to be fired.
+// Controllers reset their timers from their own goroutine, so use this
+// before calling Advance to make sure that the controller is waiting.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.pending() < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) pending() int {
+	var n int
+	for _, t := range c.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+type fakeTimer struct {
+	active   bool
+	ch       chan time.Time
+	clock    *FakeClock
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.reset(d)
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := t.active
+	t.active = false
+	t.clock.cond.Broadcast()
+	return wasActive
+}
+
+// reset must be called with the clock locked
+func (t *fakeTimer) reset(d time.Duration) bool {
+	wasActive := t.active
+	t.active = true
+	t.deadline = t.clock.now.Add(d)
+	if d <= 0 {
+		t.fire()
+	}
+	t.clock.cond.Broadcast()
+	return wasActive
+}
+
+// fire must be called with the clock locked. As with time.Timer, the
+// value is dropped if the previous one has not been received yet
+func (t *fakeTimer) fire() {
+	t.active = false
+	select {
+	case t.ch <- t.clock.now:
+	default:
+	}
+}
@@ -0,0 +1,95 @@
+package backofftest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	t.Run("Timer", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		start := clock.Now()
+
+		t1 := clock.NewTimer(time.Second)
+		t2 := clock.NewTimer(2 * time.Second)
+		if !assert.Equal(t, 2, clock.Timers(), `two timers should be pending`) {
+			return
+		}
+
+		clock.Advance(time.Second)
+		select {
+		case now := <-t1.C():
+			if !assert.Equal(t, start.Add(time.Second), now, `timer should fire with the current time`) {
+				return
+			}
+		default:
+			t.Errorf(`first timer should have fired`)
+			return
+		}
+		select {
+		case <-t2.C():
+			t.Errorf(`second timer should not have fired`)
+			return
+		default:
+		}
+
+		if !assert.True(t, t2.Stop(), `Stop should report that the timer was active`) {
+			return
+		}
+		clock.Advance(time.Hour)
+		select {
+		case <-t2.C():
+			t.Errorf(`stopped timer should not fire`)
+			return
+		default:
+		}
+
+		if !assert.False(t, t1.Reset(time.Minute), `Reset should report that the timer had expired`) {
+			return
+		}
+		if !assert.Equal(t, 1, clock.Timers(), `one timer should be pending`) {
+			return
+		}
+	})
+	t.Run("Controller", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		clock := backofftest.NewFakeClock(time.Time{})
+		c := backoff.Exponential(
+			backoff.WithClock(clock),
+			backoff.WithMinInterval(time.Second),
+			backoff.WithMultiplier(2),
+			backoff.WithMaxRetries(2),
+		).Start(ctx)
+
+		if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+			return
+		}
+
+		for _, d := range []time.Duration{time.Second, 2 * time.Second} {
+			clock.BlockUntil(1)
+			clock.Advance(d - time.Nanosecond)
+			select {
+			case <-c.Next():
+				t.Errorf(`event should not fire before %s`, d)
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			clock.Advance(time.Nanosecond)
+			if !assert.True(t, backoff.Continue(c), `retry after %s should be allowed`, d) {
+				return
+			}
+		}
+
+		if !assert.False(t, backoff.Continue(c), `no more retries should be allowed`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package backofftest
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// ConformanceTimeout is the amount of time RunPolicyConformance waits
+// for each of the expected events, such as the controller stopping after
+// its last event.
+var ConformanceTimeout = 5 * time.Second
+
+// RunPolicyConformance checks that the policies created by `newPolicy`,
+// and the controllers that they start, follow the same contracts as the
+// policies in the backoff package:
+//
+//   - the first event is fired immediately after Start
+//   - Done is closed after the last event, and no more events are fired
+//   - Done is closed when the context is canceled, after which Continue
+//     returns false within ConformanceTimeout
+//   - no goroutines are left running after the context is canceled
+//
+// `newPolicy` is called once for each check. The policy must stop on its
+// own within ConformanceTimeout, so use small intervals and a small
+// number of retries (for example backoff.WithMaxRetries(3)).
+//
+// The goroutines are counted process-wide, so RunPolicyConformance must
+// not be run in parallel with other tests.
+func RunPolicyConformance(t *testing.T, newPolicy func() backoff.Policy) {
+	t.Helper()
+
+	t.Run("FirstEventIsImmediate", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := newPolicy().Start(ctx)
+		select {
+		case _, ok := <-c.Next():
+			if !ok {
+				t.Errorf(`Next was closed before the first event`)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Errorf(`first event was not fired immediately`)
+		}
+	})
+	t.Run("DoneAfterLastEvent", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), ConformanceTimeout)
+		defer cancel()
+
+		c := newPolicy().Start(ctx)
+		var events int
+		for backoff.Continue(c) {
+			events++
+		}
+		if ctx.Err() != nil {
+			t.Fatalf(`controller did not stop within %s (after %d events)`, ConformanceTimeout, events)
+		}
+		if events < 1 {
+			t.Errorf(`controller stopped without firing any events`)
+		}
+
+		select {
+		case <-c.Done():
+		default:
+			t.Errorf(`Done was not closed after the last event`)
+		}
+
+		select {
+		case _, ok := <-c.Next():
+			if ok {
+				t.Errorf(`an event was fired after Done was closed`)
+			}
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+	t.Run("DoneAfterCancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := newPolicy().Start(ctx)
+		if !backoff.Continue(c) {
+			cancel()
+			t.Fatalf(`first event was not fired`)
+		}
+		cancel()
+
+		select {
+		case <-c.Done():
+		case <-time.After(ConformanceTimeout):
+			t.Fatalf(`Done was not closed after the context was canceled`)
+		}
+
+		deadline := time.Now().Add(ConformanceTimeout)
+		for backoff.Continue(c) {
+			if time.Now().After(deadline) {
+				t.Errorf(`events were still fired %s after the context was canceled`, ConformanceTimeout)
+				return
+			}
+		}
+	})
+	t.Run("NoGoroutineLeak", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		for i := 0; i < 10; i++ {
+			backoff.Continue(newPolicy().Start(ctx))
+		}
+		cancel()
+
+		deadline := time.Now().Add(ConformanceTimeout)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				t.Errorf(`%d goroutine(s) still running after the context was canceled`, runtime.NumGoroutine()-before)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
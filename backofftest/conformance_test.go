@@ -0,0 +1,23 @@
+package backofftest_test
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Run("Instant", func(t *testing.T) {
+		backofftest.RunPolicyConformance(t, func() backoff.Policy {
+			return backofftest.Instant(3)
+		})
+	})
+	t.Run("RecordingPolicy", func(t *testing.T) {
+		backofftest.RunPolicyConformance(t, func() backoff.Policy {
+			return backofftest.NewRecordingPolicy(func() backoff.IntervalGenerator {
+				return backoff.NewExponentialInterval()
+			}, backoff.WithMaxRetries(3))
+		})
+	})
+}
@@ -0,0 +1,6 @@
+// Package backofftest contains utilities for testing code that uses
+// the backoff package: policies that do not wait (Instant,
+// RecordingPolicy), a controller driven by the test (ScriptedController),
+// a fake clock (FakeClock), a process-wide time scale (ScaleTime), and
+// assertions (AssertSchedule, VerifyNoLeaks, RunPolicyConformance).
+package backofftest
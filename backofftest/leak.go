@@ -0,0 +1,118 @@
+package backofftest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// LeakTimeout is the amount of time VerifyNoLeaks waits for the
+// controller goroutines to stop before reporting them as leaked.
+var LeakTimeout = time.Second
+
+// VerifyNoLeaks reports an error if the goroutines of controllers started
+// during the test are still running when the test ends (including its
+// subtests). This usually means that the context passed to Policy.Start
+// was never canceled. Call it at the beginning of the test:
+//
+//	func TestFoo(t *testing.T) {
+//		backofftest.VerifyNoLeaks(t)
+//		...
+//	}
+//
+// Controller goroutines are counted process-wide (see
+// backoff.RunningControllers), and leaked goroutines are described using
+// their profiler labels (see backoff.PolicyLabel). Therefore it must not
+// be used in tests that run in parallel with other tests that start
+// controllers.
+func VerifyNoLeaks(t testing.TB) {
+	t.Helper()
+	verifyNoLeaks(t, LeakTimeout)
+}
+
+func verifyNoLeaks(t testing.TB, timeout time.Duration) {
+	before := backoff.RunningControllers()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(timeout)
+		for {
+			leaked := backoff.RunningControllers() - before
+			if leaked <= 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("backofftest: %d controller goroutine(s) leaked. Running controllers:\n%s", leaked, formatGoroutines(controllerGoroutines()))
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// controllerGoroutines returns the number of controller goroutines,
+// keyed by the description of their labels
+func controllerGoroutines() map[string]int {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+
+	// The profile consists of records such as
+	//
+	//   2 @ 0x47d82a 0x480985
+	//   # labels: {"backoff.name":"x", "backoff.policy":"null"}
+	//   #	0x480984	time.Sleep+0x164	...
+	running := make(map[string]int)
+	var count int
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, " @ "); i > 0 {
+			count, _ = strconv.Atoi(line[:i])
+			continue
+		}
+
+		const prefix = "# labels: "
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+
+		var labels map[string]string
+		if err := json.Unmarshal([]byte(line[len(prefix):]), &labels); err != nil {
+			continue
+		}
+		policy, ok := labels[backoff.PolicyLabel]
+		if !ok {
+			continue
+		}
+
+		desc := "policy=" + policy
+		if name, ok := labels[backoff.NameLabel]; ok {
+			desc += " name=" + name
+		}
+		running[desc] += count
+	}
+	return running
+}
+
+func formatGoroutines(running map[string]int) string {
+	descs := make([]string, 0, len(running))
+	for desc := range running {
+		descs = append(descs, desc)
+	}
+	sort.Strings(descs)
+
+	var sb strings.Builder
+	for _, desc := range descs {
+		fmt.Fprintf(&sb, "\t%d x %s\n", running[desc], desc)
+	}
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package backofftest
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTB captures the cleanup functions and the errors reported
+// by VerifyNoLeaks, so that failures can be tested
+type recordingTB struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Cleanup(fn func()) {
+	tb.cleanups = append(tb.cleanups, fn)
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *recordingTB) finish() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+	t.Run("Leaked", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Other controllers may stop while this runs, so the leak is
+		// checked using the labels of this controller only
+		const desc = "policy=constant(interval=1h0m0s) name=TestVerifyNoLeaks/Leaked"
+		tb := &recordingTB{}
+		verifyNoLeaks(tb, 50*time.Millisecond)
+		c := backoff.Constant(
+			backoff.WithName("TestVerifyNoLeaks/Leaked"),
+			backoff.WithInterval(time.Hour),
+		).Start(ctx)
+		tb.finish()
+
+		if !assert.Equal(t, 1, controllerGoroutines()[desc], `the leaked controller should be running`) {
+			return
+		}
+		for _, err := range tb.errors {
+			if !assert.Contains(t, err, desc, `the leaked controller should be described`) {
+				return
+			}
+		}
+		runtime.KeepAlive(c)
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		tb := &recordingTB{}
+		verifyNoLeaks(tb, time.Second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		c := backoff.Exponential(backoff.WithName("TestVerifyNoLeaks/Canceled")).Start(ctx)
+		backoff.Continue(c)
+		backoff.Null().Start(ctx)
+		cancel()
+		tb.finish()
+
+		if !assert.Empty(t, tb.errors, `no leaks should be reported`) {
+			return
+		}
+	})
+	t.Run("VerifyNoLeaks", func(t *testing.T) {
+		VerifyNoLeaks(t)
+
+		c := backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(2),
+		).Start(context.Background())
+		for backoff.Continue(c) {
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package backofftest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// Instant creates a policy whose controllers fire `n` events without
+// any delay, then stop. Use it in place of the real policy to cover
+// the retry paths of your code without waiting. If `n` is less than 1,
+// a single event is fired, as with backoff.Null().
+func Instant(n int, options ...backoff.ControllerOption) backoff.Policy {
+	return &instantPolicy{
+		cOptions: append([]backoff.ControllerOption{backoff.WithMaxRetries(0)}, options...),
+		n:        n,
+	}
+}
+
+type instantPolicy struct {
+	cOptions []backoff.ControllerOption
+	n        int
+}
+
+func (p *instantPolicy) Start(ctx context.Context) backoff.Controller {
+	return backoff.NewController(ctx, &instantInterval{remaining: p.n - 1}, p.cOptions...)
+}
+
+type instantInterval struct {
+	remaining int
+}
+
+func (g *instantInterval) Next() time.Duration {
+	if g.remaining <= 0 {
+		return -1
+	}
+	g.remaining--
+	return 0
+}
+
+// RecordingPolicy is a policy that records the intervals requested by
+// its interval generators, but fires the events without any delay.
+// Use it to check the intervals that your code would wait for, without
+// actually waiting.
+type RecordingPolicy struct {
+	cOptions     []backoff.ControllerOption
+	intervals    []time.Duration
+	mu           *sync.Mutex
+	newGenerator func() backoff.IntervalGenerator
+}
+
+// NewRecordingPolicy creates a new RecordingPolicy. `newGenerator` is
+// called every time a controller is started, for example:
+//
+//	p := backofftest.NewRecordingPolicy(func() backoff.IntervalGenerator {
+//		return backoff.NewExponentialInterval()
+//	}, backoff.WithMaxRetries(3))
+//
+// As with the policies in the backoff package, the controllers try up to
+// 10 times unless WithMaxRetries is specified.
+func NewRecordingPolicy(newGenerator func() backoff.IntervalGenerator, options ...backoff.ControllerOption) *RecordingPolicy {
+	return &RecordingPolicy{
+		cOptions:     options,
+		mu:           &sync.Mutex{},
+		newGenerator: newGenerator,
+	}
+}
+
+func (p *RecordingPolicy) Start(ctx context.Context) backoff.Controller {
+	return backoff.NewController(ctx, &recordingInterval{
+		ig:     p.newGenerator(),
+		policy: p,
+	}, p.cOptions...)
+}
+
+// Intervals returns the intervals requested so far, by all of the
+// controllers started from this policy. Negative intervals, which stop
+// the controller, are not recorded.
+func (p *RecordingPolicy) Intervals() []time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]time.Duration(nil), p.intervals...)
+}
+
+type recordingInterval struct {
+	ig     backoff.IntervalGenerator
+	policy *RecordingPolicy
+}
+
+func (g *recordingInterval) Next() time.Duration {
+	d := g.ig.Next()
+	if d < 0 {
+		return d
+	}
+
+	g.policy.mu.Lock()
+	g.policy.intervals = append(g.policy.intervals, d)
+	g.policy.mu.Unlock()
+	return 0
+}
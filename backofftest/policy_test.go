@@ -0,0 +1,61 @@
+package backofftest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+func countAttempts(t *testing.T, p backoff.Policy) int {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var attempts int
+	c := p.Start(ctx)
+	for backoff.Continue(c) {
+		attempts++
+	}
+	if ctx.Err() != nil {
+		t.Errorf(`controller took too long`)
+	}
+	return attempts
+}
+
+func TestInstant(t *testing.T) {
+	for _, n := range []int{0, 1, 5} {
+		expected := n
+		if expected < 1 {
+			expected = 1
+		}
+		if !assert.Equal(t, expected, countAttempts(t, backofftest.Instant(n)), `Instant(%d) should fire %d events`, n, expected) {
+			return
+		}
+	}
+}
+
+func TestRecordingPolicy(t *testing.T) {
+	p := backofftest.NewRecordingPolicy(func() backoff.IntervalGenerator {
+		return backoff.NewExponentialInterval(
+			backoff.WithMinInterval(time.Minute),
+			backoff.WithMaxInterval(time.Hour),
+			backoff.WithMultiplier(2),
+		)
+	}, backoff.WithMaxRetries(3))
+
+	if !assert.Equal(t, 4, countAttempts(t, p), `policy should fire 4 events`) {
+		return
+	}
+	if !assert.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}, p.Intervals(), `intervals should be recorded`) {
+		return
+	}
+
+	countAttempts(t, p)
+	if !assert.Len(t, p.Intervals(), 6, `intervals from all controllers should be recorded`) {
+		return
+	}
+}
@@ -0,0 +1,46 @@
+package backofftest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// AssertSchedule checks that `v` produces the expected intervals, and
+// reports an error via t.Errorf if it does not. It returns true if
+// the intervals match.
+//
+// `v` must be either a backoff.IntervalGenerator such as
+// backoff.NewExponentialInterval(), whose Next method is called
+// len(expected) times, or a backoff.AttemptIntervalGenerator such as
+// backoff.NewExponentialPolicy(), whose IntervalFor method is called
+// for the attempts 1 through len(expected). Jitter must be disabled,
+// otherwise the intervals can not be predicted.
+func AssertSchedule(t testing.TB, v interface{}, expected []time.Duration) bool {
+	t.Helper()
+
+	var next func(int) time.Duration
+	switch v := v.(type) {
+	case backoff.IntervalGenerator:
+		next = func(int) time.Duration { return v.Next() }
+	case backoff.AttemptIntervalGenerator:
+		next = v.IntervalFor
+	default:
+		t.Errorf("backofftest: %T is neither an IntervalGenerator nor an AttemptIntervalGenerator", v)
+		return false
+	}
+
+	actual := make([]time.Duration, len(expected))
+	for i := range expected {
+		actual[i] = next(i + 1)
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("backofftest: interval #%d does not match: expected %s, got %s\n\texpected: %v\n\tactual:   %v", i+1, expected[i], actual[i], expected, actual)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package backofftest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Helper() {}
+
+func (r *errorRecorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertSchedule(t *testing.T) {
+	options := []backoff.ExponentialOption{
+		backoff.WithMinInterval(time.Second),
+		backoff.WithMaxInterval(4 * time.Second),
+		backoff.WithMultiplier(2),
+	}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+
+	t.Run("IntervalGenerator", func(t *testing.T) {
+		backofftest.AssertSchedule(t, backoff.NewExponentialInterval(options...), expected)
+	})
+	t.Run("AttemptIntervalGenerator", func(t *testing.T) {
+		backofftest.AssertSchedule(t, backoff.NewExponentialPolicy(options...), expected)
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		r := &errorRecorder{}
+		if !assert.False(t, backofftest.AssertSchedule(r, backoff.NewConstantInterval(backoff.WithInterval(time.Second)), expected), `schedule should not match`) {
+			return
+		}
+		if !assert.Len(t, r.errors, 1, `an error should be reported`) {
+			return
+		}
+		if !assert.Contains(t, r.errors[0], "interval #2", `the first mismatch should be reported`) {
+			return
+		}
+	})
+	t.Run("Unsupported", func(t *testing.T) {
+		r := &errorRecorder{}
+		if !assert.False(t, backofftest.AssertSchedule(r, backoff.Null(), expected), `Null should not be supported`) {
+			return
+		}
+		if !assert.Len(t, r.errors, 1, `an error should be reported`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package backofftest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// ScriptedController is a backoff.Controller whose events are fired by
+// the test. It also implements backoff.Policy, whose Start method returns
+// the controller itself, so it can be passed to the code being tested:
+//
+//	c := backofftest.NewScriptedController()
+//	go doSomething(c) // calls c.Start(ctx), then loops over backoff.Continue
+//	c.Tick()          // the first attempt
+//	c.Tick()          // the first retry
+//	c.Stop()          // no more retries
+type ScriptedController struct {
+	done chan struct{}
+	next chan struct{}
+	once sync.Once
+}
+
+// NewScriptedController creates a new ScriptedController.
+func NewScriptedController() *ScriptedController {
+	return &ScriptedController{
+		done: make(chan struct{}),
+		next: make(chan struct{}),
+	}
+}
+
+// Start returns the controller itself. The context is ignored.
+func (c *ScriptedController) Start(context.Context) backoff.Controller {
+	return c
+}
+
+func (c *ScriptedController) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *ScriptedController) Next() <-chan struct{} {
+	return c.next
+}
+
+// Tick fires an event, and blocks until it has been received.
+// It returns false without firing if the controller has been stopped.
+func (c *ScriptedController) Tick() bool {
+	select {
+	case <-c.done:
+		return false
+	case c.next <- struct{}{}:
+		return true
+	}
+}
+
+// TickContext is the same as Tick, but gives up when the context is done.
+func (c *ScriptedController) TickContext(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-c.done:
+		return false
+	case c.next <- struct{}{}:
+		return true
+	}
+}
+
+// Stop closes the Done channel of the controller. It is safe to call
+// Stop multiple times.
+func (c *ScriptedController) Stop() {
+	c.once.Do(func() { close(c.done) })
+}
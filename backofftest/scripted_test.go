@@ -0,0 +1,39 @@
+package backofftest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScriptedController(t *testing.T) {
+	c := backofftest.NewScriptedController()
+
+	attempts := make(chan int)
+	go func(p backoff.Policy) {
+		var n int
+		ctrl := p.Start(context.Background())
+		for backoff.Continue(ctrl) {
+			n++
+		}
+		attempts <- n
+	}(c)
+
+	for i := 0; i < 3; i++ {
+		if !assert.True(t, c.Tick(), `tick should be delivered`) {
+			return
+		}
+	}
+	c.Stop()
+	c.Stop() // should not panic
+
+	if !assert.Equal(t, 3, <-attempts, `three attempts should have been made`) {
+		return
+	}
+	if !assert.False(t, c.Tick(), `tick should not be delivered after Stop`) {
+		return
+	}
+}
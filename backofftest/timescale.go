@@ -0,0 +1,19 @@
+package backofftest
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// ScaleTime sets the process-wide time scale via backoff.SetTimeScale,
+// and restores the previous scale when the test ends. For example,
+// ScaleTime(t, 0.001) makes every controller wait 1000 times less.
+//
+// The time scale is process-wide, so ScaleTime must not be used in
+// tests that run in parallel with tests that rely on real intervals.
+func ScaleTime(t testing.TB, v float64) {
+	t.Helper()
+	prev := backoff.SetTimeScale(v)
+	t.Cleanup(func() { backoff.SetTimeScale(prev) })
+}
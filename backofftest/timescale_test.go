@@ -0,0 +1,22 @@
+package backofftest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleTime(t *testing.T) {
+	t.Run("Scaled", func(t *testing.T) {
+		backofftest.ScaleTime(t, 0.5)
+		if !assert.Equal(t, time.Second, backoff.ScaleDuration(2*time.Second), `durations should be scaled`) {
+			return
+		}
+	})
+	if !assert.Equal(t, 2*time.Second, backoff.ScaleDuration(2*time.Second), `scale should be restored after the test`) {
+		return
+	}
+}
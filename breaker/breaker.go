@@ -0,0 +1,357 @@
+// Package breaker implements a circuit breaker that composes with the
+// backoff policies.
+//
+// A Breaker is closed (requests are allowed) until the failures reach a
+// threshold, at which point it trips and becomes open (requests are
+// rejected with ErrOpen). After a cooldown it becomes half-open, and
+// allows a few requests to probe the dependency: if they succeed the
+// breaker is closed, otherwise it is opened again. The cooldown is taken
+// from a backoff policy, so repeated trips keep the breaker open longer
+// and longer.
+//
+// Inside a backoff loop, ask the breaker before each attempt, and report
+// the result afterwards through the token returned by Allow:
+//
+//	c := policy.Start(ctx)
+//	for backoff.Continue(c) {
+//		token, err := b.Allow()
+//		if err != nil {
+//			return err // do not hammer a dependency that is down
+//		}
+//		err = op()
+//		token.Done(err)
+//		if err == nil {
+//			return nil
+//		}
+//	}
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// ErrOpen is returned by Allow and Do when the breaker does not allow
+// any requests
+var ErrOpen = errors.New(`breaker: circuit breaker is open`)
+
+// State is the state of a Breaker
+type State int
+
+const (
+	// Closed allows all requests
+	Closed State = iota
+	// Open rejects all requests until the cooldown has elapsed
+	Open
+	// HalfOpen allows a limited number of requests to probe whether
+	// the dependency has recovered
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultConsecutiveFailures = 5
+	defaultHalfOpenRequests    = 1
+	defaultProbeTimeout        = time.Minute
+	defaultWindow              = 10 * time.Second
+)
+
+// Breaker is a circuit breaker. It is safe to be used concurrently.
+type Breaker struct {
+	clock               backoff.Clock
+	consecutiveFailures int
+	cooldown            backoff.AttemptIntervalGenerator
+	failureRate         failureRate
+	halfOpenRequests    int
+	mu                  *sync.Mutex
+	onStateChange       func(from, to State)
+	probeTimeout        time.Duration
+
+	// mutable state, protected by mu
+	changes    []stateChange // not yet reported to onStateChange
+	failures   int           // consecutive failures while closed
+	generation uint64        // incremented on every state change
+	openUntil  time.Time
+	probedAt   time.Time // when the latest probe was allowed
+	probes     int       // requests allowed while half-open
+	state      State
+	successes  int // successful probes while half-open
+	trips      int // consecutive trips
+	window     *window
+}
+
+// Token is returned by Breaker.Allow, and is used to report the result
+// of the request that was allowed. Results of requests that were allowed
+// before the latest state change of the breaker are ignored, so that a
+// slow request allowed while the breaker was closed is not mistaken for
+// a probe, for example. The zero value, which Allow returns along with
+// ErrOpen, ignores the results.
+type Token struct {
+	b          *Breaker
+	generation uint64
+}
+
+// Done reports the result of the request: Success if err is nil,
+// Failure otherwise.
+func (t Token) Done(err error) {
+	if err != nil {
+		t.Failure()
+		return
+	}
+	t.Success()
+}
+
+// Success reports that the request has succeeded.
+func (t Token) Success() {
+	if t.b != nil {
+		t.b.success(t.generation)
+	}
+}
+
+// Failure reports that the request has failed.
+func (t Token) Failure() {
+	if t.b != nil {
+		t.b.failure(t.generation)
+	}
+}
+
+type stateChange struct {
+	from, to State
+}
+
+// New creates a new Breaker. See the options for the default values
+func New(options ...Option) *Breaker {
+	clock := backoff.SystemClock()
+	consecutiveFailures := defaultConsecutiveFailures
+	var cooldown backoff.AttemptIntervalGenerator
+	var rate failureRate
+	halfOpenRequests := defaultHalfOpenRequests
+	var onStateChange func(State, State)
+	probeTimeout := defaultProbeTimeout
+	windowSize := defaultWindow
+
+	for _, option := range options {
+		switch option.Ident() {
+		case identClock{}:
+			clock = option.Value().(backoff.Clock)
+		case identConsecutiveFailures{}:
+			consecutiveFailures = option.Value().(int)
+		case identCooldown{}:
+			cooldown = option.Value().(backoff.AttemptIntervalGenerator)
+		case identFailureRate{}:
+			rate = option.Value().(failureRate)
+		case identHalfOpenRequests{}:
+			halfOpenRequests = option.Value().(int)
+		case identOnStateChange{}:
+			onStateChange = option.Value().(func(State, State))
+		case identProbeTimeout{}:
+			probeTimeout = option.Value().(time.Duration)
+		case identWindow{}:
+			windowSize = option.Value().(time.Duration)
+		}
+	}
+
+	if cooldown == nil {
+		cooldown = backoff.NewExponentialPolicy(
+			backoff.WithMinInterval(5*time.Second),
+			backoff.WithMaxInterval(5*time.Minute),
+			backoff.WithMultiplier(2),
+		)
+	}
+	if halfOpenRequests < 1 {
+		halfOpenRequests = 1
+	}
+	if probeTimeout <= 0 {
+		probeTimeout = defaultProbeTimeout
+	}
+
+	return &Breaker{
+		clock:               clock,
+		consecutiveFailures: consecutiveFailures,
+		cooldown:            cooldown,
+		failureRate:         rate,
+		halfOpenRequests:    halfOpenRequests,
+		mu:                  &sync.Mutex{},
+		onStateChange:       onStateChange,
+		probeTimeout:        probeTimeout,
+		window:              newWindow(windowSize),
+	}
+}
+
+// State returns the current state of the breaker. An open breaker whose
+// cooldown has elapsed is reported as half-open.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	state := b.refresh()
+	b.unlock()
+	return state
+}
+
+// Allow reports whether a request may be made. It returns ErrOpen if
+// the breaker is open, or if it is half-open and the maximum number of
+// probes are in flight. Otherwise the result of the request must be
+// reported via the returned Token.
+//
+// Probes that are not reported within the probe timeout (see
+// WithProbeTimeout) are considered to have failed.
+func (b *Breaker) Allow() (Token, error) {
+	b.mu.Lock()
+	var err error
+	switch b.refresh() {
+	case Open:
+		err = ErrOpen
+	case HalfOpen:
+		if b.probes >= b.halfOpenRequests {
+			err = ErrOpen
+		} else {
+			b.probes++
+			b.probedAt = b.clock.Now()
+		}
+	}
+	var token Token
+	if err == nil {
+		token = Token{b: b, generation: b.generation}
+	}
+	b.unlock()
+	return token, err
+}
+
+func (b *Breaker) success(generation uint64) {
+	b.mu.Lock()
+	state := b.refresh()
+	if generation != b.generation {
+		b.unlock()
+		return
+	}
+
+	switch state {
+	case Closed:
+		b.failures = 0
+		b.window.record(b.clock.Now(), false)
+	case HalfOpen:
+		b.successes++
+		if b.successes >= b.halfOpenRequests {
+			b.transition(Closed)
+		}
+	}
+	b.unlock()
+}
+
+func (b *Breaker) failure(generation uint64) {
+	b.mu.Lock()
+	state := b.refresh()
+	if generation != b.generation {
+		b.unlock()
+		return
+	}
+
+	switch state {
+	case Closed:
+		b.failures++
+		now := b.clock.Now()
+		b.window.record(now, true)
+		if b.shouldTrip(now) {
+			b.transition(Open)
+		}
+	case HalfOpen:
+		b.transition(Open)
+	}
+	b.unlock()
+}
+
+// Do calls fn if the breaker allows it, and reports its result.
+// It returns ErrOpen without calling fn if the breaker is open.
+func (b *Breaker) Do(fn func() error) error {
+	token, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	token.Done(err)
+	return err
+}
+
+// shouldTrip must be called with the breaker locked
+func (b *Breaker) shouldTrip(now time.Time) bool {
+	if b.consecutiveFailures > 0 && b.failures >= b.consecutiveFailures {
+		return true
+	}
+
+	if b.failureRate.rate > 0 {
+		requests, failures := b.window.counts(now)
+		if requests > 0 && requests >= b.failureRate.minRequests && float64(failures)/float64(requests) >= b.failureRate.rate {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh moves an open breaker to half-open once its cooldown has
+// elapsed, and a half-open breaker back to open if its probes have not
+// been reported in time. It returns the current state, and must be
+// called with the breaker locked
+func (b *Breaker) refresh() State {
+	now := b.clock.Now()
+	switch b.state {
+	case Open:
+		if !now.Before(b.openUntil) {
+			return b.transition(HalfOpen)
+		}
+	case HalfOpen:
+		if b.probes >= b.halfOpenRequests && !now.Before(b.probedAt.Add(b.probeTimeout)) {
+			return b.transition(Open)
+		}
+	}
+	return b.state
+}
+
+// transition must be called with the breaker locked
+func (b *Breaker) transition(to State) State {
+	b.changes = append(b.changes, stateChange{from: b.state, to: to})
+	b.generation++
+	switch to {
+	case Closed:
+		b.trips = 0
+		b.failures = 0
+		b.window.reset()
+	case Open:
+		b.trips++
+		b.openUntil = b.clock.Now().Add(b.cooldown.IntervalFor(b.trips))
+	case HalfOpen:
+		b.probes = 0
+		b.successes = 0
+	}
+	b.state = to
+	return to
+}
+
+// unlock unlocks the breaker, then reports the state changes made while
+// it was locked. The callback is called without the lock, so that it
+// may use the breaker
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+
+	if b.onStateChange == nil {
+		return
+	}
+	for _, change := range changes {
+		b.onStateChange(change.from, change.to)
+	}
+}
@@ -0,0 +1,272 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/lestrrat-go/backoff/v2/breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+type transition struct {
+	from, to breaker.State
+}
+
+var errFailed = errors.New("failed")
+
+// request makes a request through the breaker, and reports `err` as its
+// result. It returns the error from Allow
+func request(b *breaker.Breaker, err error) error {
+	token, aerr := b.Allow()
+	if aerr != nil {
+		return aerr
+	}
+	token.Done(err)
+	return nil
+}
+
+// allow asks the breaker for a request, and returns the error from Allow
+func allow(b *breaker.Breaker) error {
+	_, err := b.Allow()
+	return err
+}
+
+func TestBreaker(t *testing.T) {
+	t.Run("ConsecutiveFailures", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		var transitions []transition
+		b := breaker.New(
+			breaker.WithClock(clock),
+			breaker.WithConsecutiveFailures(3),
+			breaker.WithCooldown(backoff.NewExponentialPolicy(
+				backoff.WithMinInterval(time.Second),
+				backoff.WithMaxInterval(time.Minute),
+				backoff.WithMultiplier(2),
+			)),
+			breaker.WithOnStateChange(func(from, to breaker.State) {
+				transitions = append(transitions, transition{from, to})
+			}),
+		)
+
+		request(b, errFailed)
+		request(b, errFailed)
+		request(b, nil) // resets the consecutive failures
+		request(b, errFailed)
+		request(b, errFailed)
+		if !assert.Equal(t, breaker.Closed, b.State(), `breaker should still be closed`) {
+			return
+		}
+		request(b, errFailed)
+		if !assert.Equal(t, breaker.Open, b.State(), `breaker should be open`) {
+			return
+		}
+		if !assert.Equal(t, breaker.ErrOpen, allow(b), `requests should be rejected`) {
+			return
+		}
+
+		// first trip: 1 second cooldown
+		clock.Advance(time.Second)
+		probe, err := b.Allow()
+		if !assert.NoError(t, err, `a probe should be allowed`) {
+			return
+		}
+		if !assert.Equal(t, breaker.ErrOpen, allow(b), `only one probe should be allowed`) {
+			return
+		}
+		probe.Failure()
+
+		// second trip: 2 seconds cooldown
+		clock.Advance(time.Second)
+		if !assert.Equal(t, breaker.Open, b.State(), `breaker should stay open longer after the second trip`) {
+			return
+		}
+		clock.Advance(time.Second)
+		if !assert.NoError(t, request(b, nil), `a probe should be allowed`) {
+			return
+		}
+		if !assert.Equal(t, breaker.Closed, b.State(), `breaker should be closed after a successful probe`) {
+			return
+		}
+
+		expected := []transition{
+			{breaker.Closed, breaker.Open},
+			{breaker.Open, breaker.HalfOpen},
+			{breaker.HalfOpen, breaker.Open},
+			{breaker.Open, breaker.HalfOpen},
+			{breaker.HalfOpen, breaker.Closed},
+		}
+		if !assert.Equal(t, expected, transitions, `transitions should be reported`) {
+			return
+		}
+	})
+	t.Run("FailureRate", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		b := breaker.New(
+			breaker.WithClock(clock),
+			breaker.WithConsecutiveFailures(0),
+			breaker.WithFailureRate(0.5, 4),
+			breaker.WithWindow(10*time.Second),
+		)
+
+		request(b, errFailed)
+		request(b, errFailed)
+		request(b, errFailed)
+		if !assert.Equal(t, breaker.Closed, b.State(), `breaker should not trip before the minimum number of requests`) {
+			return
+		}
+
+		// the failures fall out of the window
+		clock.Advance(10 * time.Second)
+		request(b, nil)
+		request(b, nil)
+		request(b, errFailed)
+		if !assert.Equal(t, breaker.Closed, b.State(), `old failures should not count`) {
+			return
+		}
+		request(b, errFailed)
+		if !assert.Equal(t, breaker.Open, b.State(), `breaker should trip at 50%% failures`) {
+			return
+		}
+	})
+	t.Run("HalfOpenRequests", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		b := breaker.New(
+			breaker.WithClock(clock),
+			breaker.WithConsecutiveFailures(1),
+			breaker.WithCooldown(backoff.NewConstantPolicy(backoff.WithInterval(time.Second))),
+			breaker.WithHalfOpenRequests(2),
+		)
+
+		request(b, errFailed)
+		clock.Advance(time.Second)
+		var probes []breaker.Token
+		for i := 0; i < 2; i++ {
+			probe, err := b.Allow()
+			if !assert.NoError(t, err, `probe #%d should be allowed`, i+1) {
+				return
+			}
+			probes = append(probes, probe)
+		}
+		probes[0].Success()
+		if !assert.Equal(t, breaker.HalfOpen, b.State(), `breaker should wait for all probes`) {
+			return
+		}
+		probes[1].Success()
+		if !assert.Equal(t, breaker.Closed, b.State(), `breaker should be closed`) {
+			return
+		}
+	})
+	t.Run("Stale results", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		b := breaker.New(
+			breaker.WithClock(clock),
+			breaker.WithConsecutiveFailures(1),
+			breaker.WithCooldown(backoff.NewConstantPolicy(backoff.WithInterval(time.Second))),
+		)
+
+		// a slow request is allowed while the breaker is closed, and
+		// finishes after the breaker has become half-open
+		slow, err := b.Allow()
+		if !assert.NoError(t, err, `request should be allowed`) {
+			return
+		}
+		request(b, errFailed)
+		clock.Advance(time.Second)
+		probe, err := b.Allow()
+		if !assert.NoError(t, err, `a probe should be allowed`) {
+			return
+		}
+
+		slow.Success()
+		if !assert.Equal(t, breaker.HalfOpen, b.State(), `result of the slow request should not count as a probe`) {
+			return
+		}
+		probe.Success()
+		if !assert.Equal(t, breaker.Closed, b.State(), `breaker should be closed after a successful probe`) {
+			return
+		}
+
+		var zero breaker.Token
+		zero.Failure() // should not panic
+	})
+	t.Run("ProbeTimeout", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		b := breaker.New(
+			breaker.WithClock(clock),
+			breaker.WithConsecutiveFailures(1),
+			breaker.WithCooldown(backoff.NewConstantPolicy(backoff.WithInterval(time.Second))),
+			breaker.WithProbeTimeout(10*time.Second),
+		)
+
+		request(b, errFailed)
+		clock.Advance(time.Second)
+		lost, err := b.Allow()
+		if !assert.NoError(t, err, `a probe should be allowed`) {
+			return
+		}
+
+		// the probe is never reported
+		clock.Advance(9 * time.Second)
+		if !assert.Equal(t, breaker.ErrOpen, allow(b), `only one probe should be allowed`) {
+			return
+		}
+		clock.Advance(time.Second)
+		if !assert.Equal(t, breaker.Open, b.State(), `breaker should be opened again after the probe timeout`) {
+			return
+		}
+
+		clock.Advance(time.Second)
+		probe, err := b.Allow()
+		if !assert.NoError(t, err, `a new probe should be allowed`) {
+			return
+		}
+		lost.Failure()
+		if !assert.Equal(t, breaker.HalfOpen, b.State(), `result of the lost probe should be ignored`) {
+			return
+		}
+		probe.Success()
+		if !assert.Equal(t, breaker.Closed, b.State(), `breaker should be closed after a successful probe`) {
+			return
+		}
+	})
+	t.Run("Do", func(t *testing.T) {
+		b := breaker.New(breaker.WithConsecutiveFailures(1))
+
+		if !assert.Equal(t, errFailed, b.Do(func() error { return errFailed }), `error should be returned`) {
+			return
+		}
+
+		var called bool
+		if !assert.Equal(t, breaker.ErrOpen, b.Do(func() error { called = true; return nil }), `ErrOpen should be returned`) {
+			return
+		}
+		if !assert.False(t, called, `function should not be called`) {
+			return
+		}
+	})
+	t.Run("Continue", func(t *testing.T) {
+		b := breaker.New(breaker.WithConsecutiveFailures(2))
+		c := backofftest.Instant(10).Start(context.Background())
+
+		var attempts int
+		var err error
+		for backoff.Continue(c) {
+			var token breaker.Token
+			if token, err = b.Allow(); err != nil {
+				break
+			}
+			attempts++
+			token.Done(errFailed)
+		}
+		if !assert.Equal(t, breaker.ErrOpen, err, `loop should end with ErrOpen`) {
+			return
+		}
+		if !assert.Equal(t, 2, attempts, `breaker should stop the attempts`) {
+			return
+		}
+	})
+}
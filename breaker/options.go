@@ -0,0 +1,91 @@
+package breaker
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/option"
+)
+
+type identClock struct{}
+type identConsecutiveFailures struct{}
+type identCooldown struct{}
+type identFailureRate struct{}
+type identHalfOpenRequests struct{}
+type identOnStateChange struct{}
+type identProbeTimeout struct{}
+type identWindow struct{}
+
+// Option is an option that can be passed to New
+type Option = option.Interface
+
+type failureRate struct {
+	rate        float64
+	minRequests int
+}
+
+// WithClock specifies the clock used by the breaker. The default is
+// backoff.SystemClock()
+func WithClock(v backoff.Clock) Option {
+	return option.New(identClock{}, v)
+}
+
+// WithConsecutiveFailures specifies the number of consecutive failures
+// that trip the breaker. The default value is 5. Specify 0 to disable
+// this threshold, for example when WithFailureRate is used.
+func WithConsecutiveFailures(v int) Option {
+	return option.New(identConsecutiveFailures{}, v)
+}
+
+// WithCooldown specifies the policy that determines how long the breaker
+// stays open. The n-th consecutive trip keeps the breaker open for
+// policy.IntervalFor(n), so that a dependency that keeps failing is
+// probed less and less often. Any of the policies in the backoff package
+// except for NullPolicy can be used, for example:
+//
+//	breaker.WithCooldown(backoff.NewExponentialPolicy(
+//		backoff.WithMinInterval(time.Second),
+//		backoff.WithMaxInterval(time.Minute),
+//	))
+//
+// The default is an exponential policy starting at 5 seconds, doubling
+// up to 5 minutes.
+func WithCooldown(policy backoff.AttemptIntervalGenerator) Option {
+	return option.New(identCooldown{}, policy)
+}
+
+// WithFailureRate trips the breaker when the ratio of failures to
+// requests within the window (see WithWindow) reaches `rate`, provided
+// that at least `minRequests` requests have been made. This threshold
+// is disabled by default.
+func WithFailureRate(rate float64, minRequests int) Option {
+	return option.New(identFailureRate{}, failureRate{rate: rate, minRequests: minRequests})
+}
+
+// WithHalfOpenRequests specifies the number of requests that are allowed
+// while the breaker is half-open. If all of them succeed, the breaker is
+// closed. The default value is 1.
+func WithHalfOpenRequests(v int) Option {
+	return option.New(identHalfOpenRequests{}, v)
+}
+
+// WithOnStateChange specifies a function that is called when the state
+// of the breaker changes. It is called synchronously, after the state
+// has changed, from the goroutine that caused the change.
+func WithOnStateChange(v func(from, to State)) Option {
+	return option.New(identOnStateChange{}, v)
+}
+
+// WithProbeTimeout specifies how long the breaker waits for the results
+// of its probes once all of them are in flight. If they are not all
+// reported in time, the probes are considered to have failed, and the
+// breaker is opened again. The default value is 1 minute.
+func WithProbeTimeout(v time.Duration) Option {
+	return option.New(identProbeTimeout{}, v)
+}
+
+// WithWindow specifies the duration of the rolling window over which
+// WithFailureRate is computed. The default value is 10 seconds.
+func WithWindow(v time.Duration) Option {
+	return option.New(identWindow{}, v)
+}
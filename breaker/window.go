@@ -0,0 +1,53 @@
+package breaker
+
+import "time"
+
+const windowBuckets = 10
+
+// window counts requests and failures over a rolling window, which is
+// divided into a fixed number of buckets
+type window struct {
+	buckets [windowBuckets]bucket
+	width   time.Duration
+}
+
+type bucket struct {
+	index    int64
+	failures int
+	requests int
+}
+
+func newWindow(d time.Duration) *window {
+	width := d / windowBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &window{width: width}
+}
+
+func (w *window) record(now time.Time, failed bool) {
+	index := now.UnixNano() / int64(w.width)
+	b := &w.buckets[int(index%windowBuckets)]
+	if b.index != index {
+		*b = bucket{index: index}
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+}
+
+func (w *window) counts(now time.Time) (requests, failures int) {
+	index := now.UnixNano() / int64(w.width)
+	for _, b := range w.buckets {
+		if index-b.index < windowBuckets {
+			requests += b.requests
+			failures += b.failures
+		}
+	}
+	return requests, failures
+}
+
+func (w *window) reset() {
+	w.buckets = [windowBuckets]bucket{}
+}
@@ -0,0 +1,144 @@
+package backoff
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRetryBudgetExhausted is passed to Observer.OnGiveUp when the
+// controller stops because its RetryBudget has no retries left.
+var ErrRetryBudgetExhausted = errors.New(`backoff: retry budget exhausted`)
+
+const (
+	defaultBudgetTTL              = 10 * time.Second
+	defaultBudgetMinRetriesPerSec = 10
+	defaultBudgetRetryPercent     = 0.2
+)
+
+// RetryBudget limits the number of retries across many controllers, so
+// that retries do not multiply the load on a dependency that is already
+// failing. It is modeled after Finagle's RetryBudget, except that only
+// successful requests are counted: every success deposits a fraction of
+// a retry into the budget, and every retry withdraws a whole one. On top
+// of that, a minimum number of retries per second is always allowed, so
+// that services with little traffic can still retry.
+//
+// Deposits and withdrawals expire after the TTL of the budget, so the
+// number of retries that may be made within a TTL is:
+//
+//	minRetriesPerSecond * ttl + retryPercent * successes - retries
+//
+// Attach a budget to policies using WithRetryBudget. A single budget is
+// typically shared by all the policies that talk to the same dependency.
+// RetryBudget is safe to be used concurrently.
+type RetryBudget struct {
+	clock        Clock
+	mu           *sync.Mutex
+	reserve      float64
+	retryPercent float64
+	slots        []budgetSlot
+}
+
+// budgetSlot holds the deposits and withdrawals made within one second
+type budgetSlot struct {
+	second      int64
+	deposits    int
+	withdrawals int
+}
+
+// NewRetryBudget creates a new RetryBudget. By default, the TTL is 10
+// seconds, 10 retries per second are always allowed, and each success
+// allows 0.2 retries (i.e. retries may amount to 20% of the successful
+// requests).
+func NewRetryBudget(options ...RetryBudgetOption) *RetryBudget {
+	clock := SystemClock()
+	ttl := defaultBudgetTTL
+	minRetriesPerSec := float64(defaultBudgetMinRetriesPerSec)
+	retryPercent := defaultBudgetRetryPercent
+
+	for _, option := range options {
+		switch option.Ident() {
+		case identClock{}:
+			clock = option.Value().(Clock)
+		case identBudgetTTL{}:
+			ttl = option.Value().(time.Duration)
+		case identMinRetriesPerSecond{}:
+			minRetriesPerSec = option.Value().(float64)
+		case identRetryPercent{}:
+			retryPercent = option.Value().(float64)
+		}
+	}
+
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	if minRetriesPerSec < 0 {
+		minRetriesPerSec = 0
+	}
+	if retryPercent < 0 {
+		retryPercent = 0
+	}
+
+	return &RetryBudget{
+		clock:        clock,
+		mu:           &sync.Mutex{},
+		reserve:      minRetriesPerSec * ttl.Seconds(),
+		retryPercent: retryPercent,
+		slots:        make([]budgetSlot, int(math.Ceil(ttl.Seconds()))),
+	}
+}
+
+// Deposit records a successful request. Controllers with a RetryBudget
+// call this when the user reports a success (see FeedbackController).
+func (b *RetryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.slot().deposits++
+}
+
+// TryWithdraw withdraws a retry from the budget. It returns false if
+// no retries are left, in which case the caller should not retry.
+// Controllers with a RetryBudget call this before every retry.
+func (b *RetryBudget) TryWithdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.balance() < 1 {
+		return false
+	}
+	b.slot().withdrawals++
+	return true
+}
+
+// Balance returns the number of retries that may be made right now.
+func (b *RetryBudget) Balance() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.balance())
+}
+
+// balance must be called with the budget locked
+func (b *RetryBudget) balance() float64 {
+	now := b.clock.Now().Unix()
+	var deposits, withdrawals int
+	for _, s := range b.slots {
+		if now-s.second < int64(len(b.slots)) {
+			deposits += s.deposits
+			withdrawals += s.withdrawals
+		}
+	}
+	return b.reserve + b.retryPercent*float64(deposits) - float64(withdrawals)
+}
+
+// slot returns the slot for the current second, clearing it if it has
+// expired. It must be called with the budget locked
+func (b *RetryBudget) slot() *budgetSlot {
+	now := b.clock.Now().Unix()
+	s := &b.slots[int(now%int64(len(b.slots)))]
+	if s.second != now {
+		*s = budgetSlot{second: now}
+	}
+	return s
+}
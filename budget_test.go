@@ -0,0 +1,143 @@
+package backoff_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBudget(t *testing.T) {
+	t.Run("Balance", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		b := backoff.NewRetryBudget(
+			backoff.WithClock(clock),
+			backoff.WithBudgetTTL(2*time.Second),
+			backoff.WithMinRetriesPerSecond(1),
+			backoff.WithRetryPercent(0.5),
+		)
+
+		if !assert.Equal(t, 2, b.Balance(), `reserve should be min retries per second * TTL`) {
+			return
+		}
+
+		for i := 0; i < 4; i++ {
+			b.Deposit()
+		}
+		if !assert.Equal(t, 4, b.Balance(), `each request should deposit half a retry`) {
+			return
+		}
+
+		for i := 0; i < 4; i++ {
+			if !assert.True(t, b.TryWithdraw(), `withdrawal #%d should succeed`, i+1) {
+				return
+			}
+		}
+		if !assert.False(t, b.TryWithdraw(), `withdrawal should fail when the budget is empty`) {
+			return
+		}
+
+		clock.Advance(time.Second)
+		if !assert.Equal(t, 0, b.Balance(), `deposits and withdrawals should be kept within the TTL`) {
+			return
+		}
+
+		clock.Advance(time.Second)
+		if !assert.Equal(t, 2, b.Balance(), `deposits and withdrawals should expire after the TTL`) {
+			return
+		}
+	})
+	t.Run("Deposit on success", func(t *testing.T) {
+		b := backoff.NewRetryBudget(
+			backoff.WithMinRetriesPerSecond(0),
+			backoff.WithRetryPercent(1),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		for _, p := range []backoff.Policy{
+			backoff.Null(backoff.WithRetryBudget(b)),
+			backoff.Constant(backoff.WithRetryBudget(b)),
+		} {
+			// starting a controller or failing does not deposit
+			c := p.Start(ctx)
+			backoff.Continue(c)
+			backoff.Failure(c, nil)
+			if !assert.Equal(t, 0, b.Balance(), `%T: failure should not deposit`, p) {
+				return
+			}
+
+			c = p.Start(ctx)
+			backoff.Continue(c)
+			backoff.Success(c)
+			if !assert.Equal(t, 1, b.Balance(), `%T: success should deposit`, p) {
+				return
+			}
+			if !assert.True(t, b.TryWithdraw(), `%T: retry should be allowed`, p) {
+				return
+			}
+		}
+	})
+	t.Run("Slow success", func(t *testing.T) {
+		b := backoff.NewRetryBudget(
+			backoff.WithMinRetriesPerSecond(0.5),
+			backoff.WithRetryPercent(1),
+		)
+		p := backoff.Constant(
+			backoff.WithInterval(10*time.Millisecond),
+			backoff.WithRetryBudget(b),
+		)
+
+		// the attempts take longer than the interval, but succeed on
+		// the first try, so no retries are made
+		for i := 0; i < 3; i++ {
+			ctx, cancel := context.WithCancel(context.Background())
+			c := p.Start(ctx)
+			backoff.Continue(c)
+			time.Sleep(30 * time.Millisecond)
+			backoff.Success(c)
+			cancel()
+		}
+		if !assert.Equal(t, 8, b.Balance(), `successes should deposit without withdrawing`) {
+			return
+		}
+	})
+	t.Run("Controller", func(t *testing.T) {
+		b := backoff.NewRetryBudget(
+			backoff.WithMinRetriesPerSecond(0.1),
+			backoff.WithRetryPercent(0),
+		)
+
+		o := newRecordingObserver()
+		p := backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(5),
+			backoff.WithRetryBudget(b),
+			backoff.WithObserver(o),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// the reserve allows a single retry
+		var attempts int
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+			attempts++
+		}
+		if !o.wait(t) {
+			return
+		}
+
+		if !assert.Equal(t, 2, attempts, `only one retry should be allowed by the budget`) {
+			return
+		}
+		if !assert.Equal(t, backoff.ErrRetryBudgetExhausted, o.reason, `reason should be ErrRetryBudgetExhausted`) {
+			return
+		}
+	})
+}
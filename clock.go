@@ -0,0 +1,44 @@
+package backoff
+
+import "time"
+
+// Clock is the source of time used by controllers, RetryBudget and
+// Registry, as well as the breaker and workqueue packages. The default
+// Clock uses the time package. Tests may specify a fake clock (see
+// backofftest.FakeClock) via the WithClock options, so that they do not
+// have to actually wait.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the timer created by Clock.NewTimer. It behaves like
+// time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+// SystemClock returns the Clock that uses the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
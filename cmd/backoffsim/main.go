@@ -0,0 +1,93 @@
+// backoffsim prints the schedule that a backoff policy would follow, and
+// optionally simulates the effect of jittering on the total time spent.
+//
+// The policy is read as a JSON object from the file given by -config
+// (or from stdin), in the format of the backoffconfig package, for
+// example:
+//
+//	{"type": "exponential", "min_interval": "500ms", "max_interval": "1m", "multiplier": 1.5, "jitter_factor": 0.05, "max_retries": 10}
+//	{"type": "constant", "interval": "1s", "max_retries": 5}
+//	{"type": "null"}
+//
+// Invalid configurations cause the command to exit with a non-zero status.
+//
+// With -format csv, the schedule and the histogram of the simulation are
+// written as a single table, whose "record" column is either "schedule"
+// or "bucket".
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2/backoffconfig"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("backoffsim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFile := fs.String("config", "-", "policy configuration file (\"-\" reads from stdin)")
+	format := fs.String("format", "text", "output format: text, csv, or json")
+	limit := fs.Int("limit", 20, "maximum number of attempts to compute when the policy retries forever")
+	runs := fs.Int("simulate", 0, "number of jittered runs to simulate (0 disables simulation)")
+	bins := fs.Int("bins", 20, "number of histogram buckets used to summarize simulations")
+	seed := fs.Int64("seed", 0, "random seed used for jittering (0 uses the current time)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var render func(io.Writer, []step, *simulation) error
+	switch *format {
+	case "text":
+		render = renderText
+	case "csv":
+		render = renderCSV
+	case "json":
+		render = renderJSON
+	default:
+		fmt.Fprintf(stderr, "backoffsim: unknown format %q\n", *format)
+		return 2
+	}
+	if *limit < 1 {
+		fmt.Fprintf(stderr, "backoffsim: -limit must be at least 1\n")
+		return 2
+	}
+
+	src := stdin
+	if *configFile != "-" {
+		f, err := os.Open(*configFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "backoffsim: %s\n", err)
+			return 1
+		}
+		defer f.Close()
+		src = f
+	}
+
+	cfg, err := backoffconfig.Parse(src)
+	if err != nil {
+		fmt.Fprintf(stderr, "backoffsim: %s\n", err)
+		return 1
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(*seed))
+
+	steps := schedule(cfg, rng, *limit)
+	sim := simulate(cfg, rng, *limit, *runs, *bins)
+	if err := render(stdout, steps, sim); err != nil {
+		fmt.Fprintf(stderr, "backoffsim: failed to write output: %s\n", err)
+		return 1
+	}
+	return 0
+}
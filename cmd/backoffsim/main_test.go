@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2/backoffconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Config   string
+		Expected []time.Duration
+	}{
+		{
+			Name:     "Null",
+			Config:   `{"type": "null"}`,
+			Expected: []time.Duration{0},
+		},
+		{
+			Name:     "Constant",
+			Config:   `{"type": "constant", "interval": "1s", "max_retries": 3}`,
+			Expected: []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			Name:   "Exponential",
+			Config: `{"type": "exponential", "min_interval": "1s", "multiplier": 2, "max_interval": "4s", "max_retries": 4}`,
+			Expected: []time.Duration{
+				0, time.Second, 3 * time.Second, 7 * time.Second, 11 * time.Second,
+			},
+		},
+		{
+			Name:   "Finite schedules are not capped by limit",
+			Config: `{"type": "constant", "interval": "1s", "max_retries": 6}`,
+			Expected: []time.Duration{
+				0, time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second, 5 * time.Second, 6 * time.Second,
+			},
+		},
+		{
+			Name:     "Retry forever is capped by limit",
+			Config:   `{"type": "constant", "interval": "1s", "max_retries": 0}`,
+			Expected: []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			cfg, err := backoffconfig.Parse(strings.NewReader(tc.Config))
+			if !assert.NoError(t, err, `Parse should succeed`) {
+				return
+			}
+
+			var elapsed []time.Duration
+			for _, s := range schedule(cfg, nil, 5) {
+				elapsed = append(elapsed, s.Elapsed)
+			}
+			if !assert.Equal(t, tc.Expected, elapsed, `elapsed times should match`) {
+				return
+			}
+		})
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	testcases := []string{
+		``,
+		`{}`,
+		`{"type": "linear"}`,
+		`{"type": "constant", "intervl": "1s"}`,
+		`{"type": "constant", "interval": "soon"}`,
+		`{"type": "constant", "multiplier": 2}`,
+		`{"type": "exponential", "multiplier": 0.5}`,
+		`{"type": "exponential", "min_interval": "1m", "max_interval": "1s"}`,
+		`{"type": "exponential", "jitter_factor": 1.5}`,
+		`{"type": "exponential", "max_retries": -1}`,
+		`{"type": "null"} {"type": "null"}`,
+	}
+
+	for _, tc := range testcases {
+		var stdout, stderr bytes.Buffer
+		if !assert.Equal(t, 1, run(nil, strings.NewReader(tc), &stdout, &stderr), `run should fail for %q`, tc) {
+			return
+		}
+		if !assert.NotEmpty(t, stderr.String(), `an error should be reported for %q`, tc) {
+			return
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	const config = `{"type": "exponential", "jitter_factor": 0.2, "max_retries": 3}`
+
+	t.Run("JSON", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if !assert.Equal(t, 0, run([]string{"-format", "json", "-simulate", "100", "-bins", "5", "-seed", "1"}, strings.NewReader(config), &stdout, &stderr), `run should succeed (%s)`, stderr.String()) {
+			return
+		}
+
+		var out jsonOutput
+		if !assert.NoError(t, json.Unmarshal(stdout.Bytes(), &out), `output should be valid JSON`) {
+			return
+		}
+		if !assert.Len(t, out.Schedule, 4, `schedule should contain 4 attempts`) {
+			return
+		}
+		if !assert.NotNil(t, out.Simulation, `simulation should be present`) {
+			return
+		}
+
+		var total int
+		for _, b := range out.Simulation.Buckets {
+			total += b.Count
+		}
+		if !assert.Equal(t, 100, total, `all runs should be accounted for in the histogram`) {
+			return
+		}
+	})
+	t.Run("CSV", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if !assert.Equal(t, 0, run([]string{"-format", "csv"}, strings.NewReader(config), &stdout, &stderr), `run should succeed (%s)`, stderr.String()) {
+			return
+		}
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		if !assert.Len(t, lines, 5, `header + 4 attempts`) {
+			return
+		}
+		if !assert.Equal(t, "record,attempt,interval_seconds,elapsed_seconds,bucket_start_seconds,bucket_end_seconds,count", lines[0]) {
+			return
+		}
+	})
+	t.Run("CSV with simulation", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if !assert.Equal(t, 0, run([]string{"-format", "csv", "-simulate", "100", "-bins", "10"}, strings.NewReader(config), &stdout, &stderr), `run should succeed (%s)`, stderr.String()) {
+			return
+		}
+		records, err := csv.NewReader(&stdout).ReadAll()
+		if !assert.NoError(t, err, `output should be valid CSV`) {
+			return
+		}
+
+		counts := make(map[string]int)
+		for _, record := range records[1:] {
+			counts[record[0]]++
+		}
+		if !assert.Equal(t, map[string]int{"schedule": 4, "bucket": 10}, counts, `both the schedule and the histogram should be written`) {
+			return
+		}
+	})
+	t.Run("Text", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if !assert.Equal(t, 0, run([]string{"-simulate", "100"}, strings.NewReader(config), &stdout, &stderr), `run should succeed (%s)`, stderr.String()) {
+			return
+		}
+		if !assert.Contains(t, stdout.String(), `#`, `histogram should be rendered`) {
+			return
+		}
+	})
+	t.Run("Unknown format", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if !assert.Equal(t, 2, run([]string{"-format", "xml"}, strings.NewReader(config), &stdout, &stderr)) {
+			return
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const histogramWidth = 50
+
+func renderText(dst io.Writer, steps []step, sim *simulation) error {
+	w := tabwriter.NewWriter(dst, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "attempt\tinterval\telapsed\t\n")
+	for _, s := range steps {
+		fmt.Fprintf(w, "%d\t%s\t%s\t\n", s.Attempt, s.Interval, s.Elapsed)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if sim == nil {
+		return nil
+	}
+
+	fmt.Fprintf(dst, "\ntime until last attempt (%d runs)\n", sim.Runs)
+	fmt.Fprintf(dst, "  min=%s mean=%s p50=%s p90=%s p99=%s max=%s\n\n",
+		sim.Min, sim.Mean, sim.P50, sim.P90, sim.P99, sim.Max)
+
+	var peak int
+	for _, b := range sim.Buckets {
+		if b.Count > peak {
+			peak = b.Count
+		}
+	}
+
+	w = tabwriter.NewWriter(dst, 0, 4, 1, ' ', 0)
+	for _, b := range sim.Buckets {
+		bar := strings.Repeat("#", b.Count*histogramWidth/peak)
+		fmt.Fprintf(w, "%s\t- %s\t|%s %d\n", b.Start.Round(time.Millisecond), b.End.Round(time.Millisecond), bar, b.Count)
+	}
+	return w.Flush()
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// renderCSV writes the schedule and the histogram of the simulation (if
+// any) as a single table. The first column tells which of the two a
+// record belongs to, and the columns of the other one are left empty
+func renderCSV(dst io.Writer, steps []step, sim *simulation) error {
+	w := csv.NewWriter(dst)
+	_ = w.Write([]string{"record", "attempt", "interval_seconds", "elapsed_seconds", "bucket_start_seconds", "bucket_end_seconds", "count"})
+	for _, s := range steps {
+		_ = w.Write([]string{"schedule", strconv.Itoa(s.Attempt), seconds(s.Interval), seconds(s.Elapsed), "", "", ""})
+	}
+	if sim != nil {
+		for _, b := range sim.Buckets {
+			_ = w.Write([]string{"bucket", "", "", "", seconds(b.Start), seconds(b.End), strconv.Itoa(b.Count)})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+type jsonStep struct {
+	Attempt  int     `json:"attempt"`
+	Interval float64 `json:"interval_seconds"`
+	Elapsed  float64 `json:"elapsed_seconds"`
+}
+
+type jsonBucket struct {
+	Start float64 `json:"start_seconds"`
+	End   float64 `json:"end_seconds"`
+	Count int     `json:"count"`
+}
+
+type jsonSimulation struct {
+	Runs    int          `json:"runs"`
+	Min     float64      `json:"min_seconds"`
+	Max     float64      `json:"max_seconds"`
+	Mean    float64      `json:"mean_seconds"`
+	P50     float64      `json:"p50_seconds"`
+	P90     float64      `json:"p90_seconds"`
+	P99     float64      `json:"p99_seconds"`
+	Buckets []jsonBucket `json:"buckets"`
+}
+
+type jsonOutput struct {
+	Schedule   []jsonStep      `json:"schedule"`
+	Simulation *jsonSimulation `json:"simulation,omitempty"`
+}
+
+func renderJSON(dst io.Writer, steps []step, sim *simulation) error {
+	var out jsonOutput
+	for _, s := range steps {
+		out.Schedule = append(out.Schedule, jsonStep{
+			Attempt:  s.Attempt,
+			Interval: s.Interval.Seconds(),
+			Elapsed:  s.Elapsed.Seconds(),
+		})
+	}
+	if sim != nil {
+		out.Simulation = &jsonSimulation{
+			Runs: sim.Runs,
+			Min:  sim.Min.Seconds(),
+			Max:  sim.Max.Seconds(),
+			Mean: sim.Mean.Seconds(),
+			P50:  sim.P50.Seconds(),
+			P90:  sim.P90.Seconds(),
+			P99:  sim.P99.Seconds(),
+		}
+		for _, b := range sim.Buckets {
+			out.Simulation.Buckets = append(out.Simulation.Buckets, jsonBucket{
+				Start: b.Start.Seconds(),
+				End:   b.End.Seconds(),
+				Count: b.Count,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
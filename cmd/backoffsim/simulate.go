@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backoffconfig"
+)
+
+// step is a single attempt in a backoff schedule. Interval is the time
+// spent waiting before this attempt, and Elapsed is the time since the
+// controller was started.
+type step struct {
+	Attempt  int
+	Interval time.Duration
+	Elapsed  time.Duration
+}
+
+// schedule computes the attempts that a controller created from the
+// given configuration would fire. The first attempt is always fired
+// immediately. If the policy retries forever, at most `limit` attempts
+// are computed.
+func schedule(cfg *backoffconfig.Config, rng backoff.Random, limit int) []step {
+	steps := []step{{Attempt: 1}}
+
+	ig := cfg.IntervalGenerator(rng)
+	if ig == nil {
+		return steps
+	}
+
+	retries := cfg.Retries()
+	if retries == 0 {
+		retries = limit - 1
+	}
+
+	var elapsed time.Duration
+	for i := 0; i < retries; i++ {
+		d := ig.Next()
+		elapsed += d
+		steps = append(steps, step{
+			Attempt:  i + 2,
+			Interval: d,
+			Elapsed:  elapsed,
+		})
+	}
+	return steps
+}
+
+type bucket struct {
+	Start time.Duration
+	End   time.Duration
+	Count int
+}
+
+type simulation struct {
+	Runs    int
+	Min     time.Duration
+	Max     time.Duration
+	Mean    time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Buckets []bucket
+}
+
+// simulate computes `runs` schedules and summarizes the distribution of
+// the total time spent until the last attempt is fired.
+func simulate(cfg *backoffconfig.Config, rng backoff.Random, limit, runs, bins int) *simulation {
+	if runs <= 0 {
+		return nil
+	}
+	if bins <= 0 {
+		bins = 1
+	}
+
+	totals := make([]time.Duration, runs)
+	var sum float64
+	for i := 0; i < runs; i++ {
+		steps := schedule(cfg, rng, limit)
+		totals[i] = steps[len(steps)-1].Elapsed
+		sum += float64(totals[i])
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i] < totals[j] })
+
+	sim := &simulation{
+		Runs: runs,
+		Min:  totals[0],
+		Max:  totals[runs-1],
+		Mean: time.Duration(sum / float64(runs)),
+		P50:  percentile(totals, 0.50),
+		P90:  percentile(totals, 0.90),
+		P99:  percentile(totals, 0.99),
+	}
+
+	width := float64(sim.Max-sim.Min) / float64(bins)
+	if width == 0 {
+		sim.Buckets = []bucket{{Start: sim.Min, End: sim.Max, Count: runs}}
+		return sim
+	}
+
+	sim.Buckets = make([]bucket, bins)
+	for i := range sim.Buckets {
+		sim.Buckets[i].Start = sim.Min + time.Duration(width*float64(i))
+		sim.Buckets[i].End = sim.Min + time.Duration(width*float64(i+1))
+	}
+	sim.Buckets[bins-1].End = sim.Max
+	for _, v := range totals {
+		i := int(float64(v-sim.Min) / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		sim.Buckets[i].Count++
+	}
+	return sim
+}
+
+// percentile returns the nearest-rank percentile of the sorted values
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
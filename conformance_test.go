@@ -0,0 +1,49 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+)
+
+func TestPolicyConformance(t *testing.T) {
+	t.Run("Null", func(t *testing.T) {
+		backofftest.RunPolicyConformance(t, func() backoff.Policy {
+			return backoff.Null()
+		})
+	})
+	t.Run("Constant", func(t *testing.T) {
+		backofftest.RunPolicyConformance(t, func() backoff.Policy {
+			return backoff.Constant(
+				backoff.WithInterval(10*time.Millisecond),
+				backoff.WithMaxRetries(3),
+			)
+		})
+	})
+	t.Run("Exponential", func(t *testing.T) {
+		backofftest.RunPolicyConformance(t, func() backoff.Policy {
+			return backoff.Exponential(
+				backoff.WithMinInterval(10*time.Millisecond),
+				backoff.WithMaxRetries(3),
+			)
+		})
+	})
+	t.Run("Adaptive", func(t *testing.T) {
+		backofftest.RunPolicyConformance(t, func() backoff.Policy {
+			return backoff.NewAdaptivePolicy(
+				backoff.WithMinInterval(10*time.Millisecond),
+				backoff.WithMaxRetries(3),
+			)
+		})
+	})
+	t.Run("GRPCConnect", func(t *testing.T) {
+		backofftest.RunPolicyConformance(t, func() backoff.Policy {
+			return backoff.GRPCConnect(
+				backoff.WithMinInterval(10*time.Millisecond),
+				backoff.WithMaxRetries(3),
+			)
+		})
+	})
+}
@@ -2,6 +2,7 @@ package backoff
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -14,6 +15,7 @@ func NewConstantInterval(options ...ConstantOption) *ConstantInterval {
 	jitterFactor := 0.0
 	interval := time.Minute
 	var rng Random
+	var jitterStrategy JitterStrategy
 
 	for _, option := range options {
 		switch option.Ident() {
@@ -21,6 +23,8 @@ func NewConstantInterval(options ...ConstantOption) *ConstantInterval {
 			interval = option.Value().(time.Duration)
 		case identJitterFactor{}:
 			jitterFactor = option.Value().(float64)
+		case identJitterStrategy{}:
+			jitterStrategy = option.Value().(JitterStrategy)
 		case identRNG{}:
 			rng = option.Value().(Random)
 		}
@@ -28,7 +32,7 @@ func NewConstantInterval(options ...ConstantOption) *ConstantInterval {
 
 	return &ConstantInterval{
 		interval: interval,
-		jitter:   newJitter(jitterFactor, rng),
+		jitter:   newJitter(jitterFactor, rng, jitterStrategy),
 	}
 }
 
@@ -36,9 +40,20 @@ func (g *ConstantInterval) Next() time.Duration {
 	return time.Duration(g.jitter.apply(float64(g.interval)))
 }
 
+func (g *ConstantInterval) String() string {
+	return fmt.Sprintf("constant(interval=%s)", g.interval)
+}
+
+// IntervalFor returns the interval before the n-th retry. For
+// ConstantInterval this is the same for all values of n.
+func (g *ConstantInterval) IntervalFor(int) time.Duration {
+	return g.Next()
+}
+
 type ConstantPolicy struct {
 	cOptions  []ControllerOption
 	igOptions []ConstantOption
+	stateless *statelessGenerator
 }
 
 func NewConstantPolicy(options ...Option) *ConstantPolicy {
@@ -57,9 +72,17 @@ func NewConstantPolicy(options ...Option) *ConstantPolicy {
 	return &ConstantPolicy{
 		cOptions:  cOptions,
 		igOptions: igOptions,
+		stateless: newStatelessGenerator(NewConstantInterval(igOptions...)),
 	}
 }
 
+// IntervalFor returns the interval before the n-th retry.
+// See AttemptIntervalGenerator. Unlike the interval generators, this
+// method is safe to be called concurrently
+func (p *ConstantPolicy) IntervalFor(n int) time.Duration {
+	return p.stateless.IntervalFor(n)
+}
+
 func (p *ConstantPolicy) Start(ctx context.Context) Controller {
 	ig := NewConstantInterval(p.igOptions...)
 	return newController(ctx, ig, p.cOptions...)
@@ -3,82 +3,268 @@ package backoff
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type controller struct {
+	*signals
+	attempts   int
+	budget     *RetryBudget
+	clock      Clock
 	ctx        context.Context
 	cancel     func()
+	fired      int64 // UnixNano of the time the latest event became ready
+	first      time.Duration
 	ig         IntervalGenerator
 	maxRetries int
 	mu         *sync.RWMutex
 	next       chan struct{} // user-facing channel
-	resetTimer chan time.Duration
+	parent     context.Context
 	retries    int
-	timer      *time.Timer
+	scheduled  int64 // UnixNano of the time the timer is set to fire
+	timer      Timer
 }
 
-func newController(ctx context.Context, ig IntervalGenerator, options ...ControllerOption) *controller {
+// NewController creates a new Controller that fires events at the
+// intervals generated by the given IntervalGenerator. This is what the
+// policies in this package use internally, and is provided for those
+// who want to implement their own policies.
+//
+// If the IntervalGenerator returns a negative interval, the controller
+// stops firing events and its Done channel is closed.
+func NewController(ctx context.Context, ig IntervalGenerator, options ...ControllerOption) Controller {
+	first := ig.Next()
+	if first < 0 {
+		// Not even a single retry is allowed: this is exactly
+		// what the null controller does
+		return newNullController(ctx, options...)
+	}
+	return newControllerWithInterval(ctx, ig, first, options...)
+}
+
+func newController(ctx context.Context, ig IntervalGenerator, options ...ControllerOption) Controller {
+	return newControllerWithInterval(ctx, ig, ig.Next(), options...)
+}
+
+func newControllerWithInterval(ctx context.Context, ig IntervalGenerator, first time.Duration, options ...ControllerOption) Controller {
 	cctx, cancel := context.WithCancel(ctx) // DO NOT fire this cancel here
 
 	maxRetries := 10
+	clock := SystemClock()
+	var budget *RetryBudget
+	var name string
+	observers := observersFromContext(ctx)
 	for _, option := range options {
 		switch option.Ident() {
+		case identClock{}:
+			clock = option.Value().(Clock)
 		case identMaxRetries{}:
 			maxRetries = option.Value().(int)
+		case identName{}:
+			name = option.Value().(string)
+		case identRetryBudget{}:
+			budget = option.Value().(*RetryBudget)
+		case identObserver{}:
+			observers = append(observers, option.Value().(Observer))
+		case identObserverFactory{}:
+			observers = append(observers, option.Value().(func() Observer)())
 		}
 	}
 
+	policy := describe(ig)
+	tc := track(name, policy, maxRetries)
+	if tc != nil {
+		observers = append(observers, tc)
+	}
+
 	c := &controller{
+		signals:    newSignals(newObserverQueue(observers)),
+		budget:     budget,
 		cancel:     cancel,
+		clock:      clock,
 		ctx:        cctx,
+		fired:      clock.Now().UnixNano(),
+		first:      first,
 		ig:         ig,
 		maxRetries: maxRetries,
 		mu:         &sync.RWMutex{},
-		next:       make(chan struct{}, 1),
-		resetTimer: make(chan time.Duration, 1),
-		timer:      time.NewTimer(ig.Next()),
+		next:       make(chan struct{}), // NO BUFFER: see loop
+		parent:     ctx,
+		scheduled:  clock.Now().Add(ScaleDuration(first)).UnixNano(),
+		timer:      clock.NewTimer(ScaleDuration(first)),
+	}
+	if tc != nil {
+		tc.scheduled = &c.scheduled
 	}
 
-	// enqueue a single fake event so the user gets to retry once
-	c.next <- struct{}{}
-
-	go c.loop()
-	return c
+	runLabeled(ctx, name, policy, c.loop)
+	return watchLeaks(c, func() { cancel(); c.release() }, name, policy, maxRetries)
 }
 
+// loop fires the events. As the user-facing channel is not buffered, an
+// event is only counted as an attempt once the user has received it.
+// Observers are told about a wait only when the user asks for another
+// event (see signals), so that an attempt that succeeds is not reported
+// as a scheduled retry.
 func (c *controller) loop() {
+	var reason error
+	failed := false    // the user has reported the failure of the latest attempt
+	succeeded := false // the user has reported the success of the latest attempt
+	defer func() {
+		c.cancel()
+		c.exit(c.parent, c.attempts, reason, failed, succeeded)
+	}()
+
+	// The first event is ready right away, and the wait for the
+	// second one starts at the same time
+	pending := true    // an event is ready to be received
+	due := false       // the timer fired while an event was still pending
+	scheduled := true  // the timer is running
+	announced := false // OnWait has been called for the current wait
+	withdrawn := false // a retry has been withdrawn from the budget for the current wait
+	resume := false    // the wait was stopped by a success, and resumes if the user comes back
+	wait := c.first
+
 	for {
+		var next chan struct{}
+		if pending && (withdrawn || c.budget == nil || c.attempts == 0) {
+			next = c.next
+		}
+		var timerC <-chan time.Time
+		if scheduled {
+			timerC = c.timer.C()
+		}
+
 		select {
 		case <-c.ctx.Done():
 			return
-		case d := <-c.resetTimer:
-			if !c.timer.Stop() {
-				select {
-				case <-c.timer.C:
-				default:
+		case <-c.ask:
+			// the number of calls to Next is checked below
+		case f := <-c.feedback:
+			if c.attempts == 0 {
+				// no attempt has been made yet
+				break
+			}
+			c.observers.feedback(c.attempts, f)
+			failed, succeeded = !f.Success, f.Success
+			if f.Success {
+				// there is nothing to wait for, unless the user comes
+				// back anyway
+				if scheduled {
+					c.timer.Stop()
+					scheduled, resume = false, true
 				}
+				break
 			}
-			c.timer.Reset(d)
-		case <-c.timer.C:
-			select {
-			case <-c.ctx.Done():
+			if _, ok := c.ig.(FeedbackIntervalGenerator); !ok {
+				break
+			}
+
+			// The generator has received feedback, so its next interval
+			// may be different from the one we are waiting for. The wait
+			// now starts from the time the feedback was given
+			d := c.ig.Next()
+			if d < 0 {
+				reason = ErrIntervalsExhausted
 				return
-			case c.next <- struct{}{}:
 			}
-			if c.maxRetries > 0 {
+			c.reset(d)
+			pending, due, scheduled, resume = false, false, true, false
+			wait, announced = d, false
+		case <-timerC:
+			scheduled = false
+			if pending {
+				due = true
+				break
+			}
+			pending = true
+			atomic.StoreInt64(&c.fired, c.clock.Now().UnixNano())
+		case next <- struct{}{}:
+			if c.attempts > 0 && !announced {
+				// the user waited without asking first
+				c.observers.wait(c.attempts+1, wait)
+			}
+			c.attempts++
+			c.observers.attempt(c.attempts)
+			if c.attempts > 1 && c.maxRetries > 0 {
 				c.retries++
 			}
+			failed, succeeded, announced, withdrawn = false, false, false, false
 
 			if !c.check() {
-				c.cancel()
+				reason = ErrMaxRetries
+				return
+			}
+
+			pending, due = due, false
+			if pending {
+				atomic.StoreInt64(&c.fired, c.clock.Now().UnixNano())
+			}
+			if pending || scheduled {
+				break
+			}
+
+			d := c.ig.Next()
+			if d < 0 {
+				// the interval generator has been exhausted
+				reason = ErrIntervalsExhausted
+				return
+			}
+			c.reset(d)
+			scheduled, wait = true, d
+		}
+
+		if c.attempts == 0 || !c.asked(c.attempts) {
+			if failed && !announced {
+				c.observers.wait(c.attempts+1, wait)
+				announced = true
+			}
+			continue
+		}
+
+		// The user wants another attempt
+		succeeded = false
+		if resume {
+			d := wait
+			if _, ok := c.ig.(FeedbackIntervalGenerator); ok {
+				if d = c.ig.Next(); d < 0 {
+					reason = ErrIntervalsExhausted
+					return
+				}
+			}
+			c.reset(d)
+			scheduled, resume = true, false
+			wait, announced = d, false
+		}
+		if c.budget != nil && !withdrawn {
+			if !c.budget.TryWithdraw() {
+				reason = ErrRetryBudgetExhausted
 				return
 			}
-			c.resetTimer <- c.ig.Next()
+			withdrawn = true
+		}
+		if !announced {
+			c.observers.wait(c.attempts+1, wait)
+			announced = true
 		}
 	}
 }
 
+// reset reschedules the timer to fire after `d`, scaled by the time scale,
+// and records the time it is set to fire
+func (c *controller) reset(d time.Duration) {
+	d = ScaleDuration(d)
+	atomic.StoreInt64(&c.scheduled, c.clock.Now().Add(d).UnixNano())
+	if !c.timer.Stop() {
+		select {
+		case <-c.timer.C():
+		default:
+		}
+	}
+	c.timer.Reset(d)
+}
+
 func (c *controller) check() bool {
 	if c.maxRetries > 0 && c.retries >= c.maxRetries {
 		return false
@@ -93,7 +279,152 @@ func (c *controller) Done() <-chan struct{} {
 }
 
 func (c *controller) Next() <-chan struct{} {
+	c.signalAsk()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.next
 }
+
+func (c *controller) Success() {
+	c.feedbackWith(Feedback{Success: true})
+}
+
+func (c *controller) Failure(err error) {
+	c.feedbackWith(Feedback{Success: false, Err: err})
+}
+
+func (c *controller) feedbackWith(f Feedback) {
+	f.Elapsed = c.clock.Now().Sub(time.Unix(0, atomic.LoadInt64(&c.fired)))
+	if f.Success && c.budget != nil {
+		c.budget.Deposit()
+	}
+	if g, ok := c.ig.(FeedbackIntervalGenerator); ok {
+		g.Feedback(f)
+	}
+	c.report(f)
+}
+
+// signals carries what the user of a controller does to the goroutine of
+// the controller, which needs to know whether the user has come back
+// for another event after an attempt.
+//
+// When the controller runs out of events, its goroutine exits right away,
+// but whether it gives up is only known once the user comes back: if the
+// latest attempt succeeds, there was nothing to give up on. In that case
+// the final notifications are delivered from the goroutine of the user
+// (see signalAsk and report), or once the parent context is done or the
+// controller is garbage collected, whichever comes first.
+type signals struct {
+	ask        chan struct{} // wakes the goroutine up after a call to Next
+	asks       int64         // number of calls to Next
+	feedback   chan Feedback // the user has reported the outcome of an attempt
+	finished   bool          // the final notifications have been delivered
+	mu         *sync.Mutex
+	n          int // number of events fired, set before stopped is closed
+	observers  *observerQueue
+	once       *sync.Once
+	reason     error         // why the controller ran out of events, set before stopped is closed
+	stopped    chan struct{} // the goroutine has exited
+	unregister func()        // stops waiting for the parent context
+}
+
+func newSignals(observers *observerQueue) *signals {
+	return &signals{
+		ask:       make(chan struct{}, 1),
+		feedback:  make(chan Feedback),
+		mu:        &sync.Mutex{},
+		observers: observers,
+		once:      &sync.Once{},
+		stopped:   make(chan struct{}),
+	}
+}
+
+// asked reports whether the user has asked for another event after
+// receiving the n-th one. Each round of Continue calls Next once
+func (s *signals) asked(n int) bool {
+	return atomic.LoadInt64(&s.asks) > int64(n)
+}
+
+// signalAsk is called from Next. If the goroutine has already exited,
+// the controller gives up if it has run out of events
+func (s *signals) signalAsk() {
+	atomic.AddInt64(&s.asks, 1)
+	select {
+	case s.ask <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-s.stopped:
+		if s.asked(s.n) {
+			s.finish(s.reason, nil)
+		}
+	default:
+	}
+}
+
+// report hands the feedback to the goroutine. If it has already exited,
+// a failure of the last attempt means that the controller gives up
+func (s *signals) report(f Feedback) {
+	select {
+	case s.feedback <- f:
+	case <-s.stopped:
+		if f.Success {
+			s.finish(nil, &f)
+		} else {
+			s.finish(s.reason, &f)
+		}
+	}
+}
+
+// exit is called by the goroutine when it stops after `n` events. If it
+// ran out of events (reason is not nil), and the user has neither come
+// back nor reported the outcome of the last attempt, the decision is
+// left to the goroutine of the user.
+func (s *signals) exit(parent context.Context, n int, reason error, failed, succeeded bool) {
+	s.n, s.reason = n, reason
+	close(s.stopped)
+
+	switch {
+	case reason == nil || succeeded:
+		s.finish(nil, nil)
+	case failed || s.asked(n):
+		s.finish(reason, nil)
+	default:
+		s.mu.Lock()
+		if !s.finished {
+			s.unregister = afterFunc(parent, s.release)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// release delivers the final notifications without giving up, unless the
+// goroutine is still running, in which case it will deliver them itself
+func (s *signals) release() {
+	select {
+	case <-s.stopped:
+		s.finish(nil, nil)
+	default:
+	}
+}
+
+// finish delivers the feedback for the last attempt (if any), OnGiveUp
+// (if reason is not nil) and OnStop, exactly once. It may only be called
+// after the goroutine has exited
+func (s *signals) finish(reason error, f *Feedback) {
+	s.once.Do(func() {
+		if f != nil && s.n > 0 {
+			s.observers.feedback(s.n, *f)
+		}
+		s.observers.stop(reason)
+
+		s.mu.Lock()
+		s.finished = true
+		unregister := s.unregister
+		s.mu.Unlock()
+		if unregister != nil {
+			unregister()
+		}
+	})
+}
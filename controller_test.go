@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/lestrrat-go/backoff/v2"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestLeak(t *testing.T) {
@@ -34,3 +35,56 @@ func TestLeak(t *testing.T) {
 		t.Errorf("goroutines seem to be leaked. before: %d, after: %d", beforeGoroutine, afterGoroutine)
 	}
 }
+
+type limitedInterval struct {
+	remaining int
+}
+
+func (g *limitedInterval) Next() time.Duration {
+	if g.remaining <= 0 {
+		return -1
+	}
+	g.remaining--
+	return time.Millisecond
+}
+
+func TestNewController(t *testing.T) {
+	for _, remaining := range []int{0, 1, 5} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		c := backoff.NewController(ctx, &limitedInterval{remaining: remaining}, backoff.WithMaxRetries(0))
+
+		var count int
+		for backoff.Continue(c) {
+			count++
+		}
+		if !assert.NoError(t, ctx.Err(), `controller should stop before the context is done`) {
+			cancel()
+			return
+		}
+		cancel()
+		if !assert.Equal(t, remaining+1, count, `initial + %d retries`, remaining) {
+			return
+		}
+	}
+}
+
+func TestLastEventIsNotLost(t *testing.T) {
+	// The controller closes Done right after its last event has been
+	// received, which must not race with the receipt of the event
+	for i := 0; i < 200; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		c := backoff.Constant(
+			backoff.WithInterval(time.Microsecond),
+			backoff.WithMaxRetries(2),
+		).Start(ctx)
+
+		var count int
+		for backoff.Continue(c) {
+			count++
+		}
+		cancel()
+		if !assert.Equal(t, 3, count, `all events should be received (run %d)`, i) {
+			return
+		}
+	}
+}
@@ -2,10 +2,13 @@ package backoff
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"time"
 )
 
 type ExponentialInterval struct {
+	compound    bool // the next interval grows from the jittered one
 	current     float64
 	maxInterval float64
 	minInterval float64
@@ -25,11 +28,14 @@ func NewExponentialInterval(options ...ExponentialOption) *ExponentialInterval {
 	minInterval := defaultMinInterval
 	multiplier := defaultMultiplier
 	var rng Random
+	var jitterStrategy JitterStrategy
 
 	for _, option := range options {
 		switch option.Ident() {
 		case identJitterFactor{}:
 			jitterFactor = option.Value().(float64)
+		case identJitterStrategy{}:
+			jitterStrategy = option.Value().(JitterStrategy)
 		case identMaxInterval{}:
 			maxInterval = float64(option.Value().(time.Duration))
 		case identMinInterval{}:
@@ -49,10 +55,14 @@ func NewExponentialInterval(options ...ExponentialOption) *ExponentialInterval {
 	}
 
 	return &ExponentialInterval{
+		// Positive jitter only ever extends the intervals, so letting it
+		// compound would make them grow much faster than wait.Backoff,
+		// which only jitters the returned values
+		compound:    jitterStrategy != JitterPositive,
 		maxInterval: maxInterval,
 		minInterval: minInterval,
 		multiplier:  multiplier,
-		jitter:      newJitter(jitterFactor, rng),
+		jitter:      newJitter(jitterFactor, rng, jitterStrategy),
 	}
 }
 
@@ -72,36 +82,88 @@ func (g *ExponentialInterval) Next() time.Duration {
 	}
 
 	// Apply jitter *AFTER* we calculate the base interval
-	next = g.jitter.apply(next)
+	jittered := g.jitter.apply(next)
+	if g.compound {
+		next = jittered
+	}
 	g.current = next
-	return time.Duration(next)
+	return time.Duration(jittered)
+}
+
+func (g *ExponentialInterval) String() string {
+	return fmt.Sprintf("exponential(min=%s, max=%s, multiplier=%g)", time.Duration(g.minInterval), time.Duration(g.maxInterval), g.multiplier)
+}
+
+// IntervalFor returns the interval before the n-th retry, which is the
+// same as the n-th value returned by Next when jitter is disabled.
+// Unlike Next with JitterSymmetric, jitter does not compound across
+// attempts.
+func (g *ExponentialInterval) IntervalFor(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+
+	next := math.Min(g.minInterval*math.Pow(g.multiplier, float64(n-1)), g.maxInterval)
+	if next < g.minInterval {
+		next = g.minInterval
+	}
+	return time.Duration(g.jitter.apply(next))
 }
 
 type ExponentialPolicy struct {
-	cOptions  []ControllerOption
-	igOptions []ExponentialOption
+	cOptions          []ControllerOption
+	igOptions         []ExponentialOption
+	latencyFactor     float64
+	latencyPercentile float64
+	latencyWindow     int
+	stateless         *statelessGenerator
 }
 
 func NewExponentialPolicy(options ...ExponentialOption) *ExponentialPolicy {
 	var cOptions []ControllerOption
 	var igOptions []ExponentialOption
+	var latencyFactor float64
+	latencyPercentile := defaultLatencyPercentile
+	latencyWindow := defaultLatencyWindow
 
 	for _, option := range options {
 		switch opt := option.(type) {
 		case ControllerOption:
 			cOptions = append(cOptions, opt)
 		default:
+			switch opt.Ident() {
+			case identLatencyFactor{}:
+				latencyFactor = opt.Value().(float64)
+			case identLatencyPercentile{}:
+				latencyPercentile = opt.Value().(float64)
+			case identLatencyWindow{}:
+				latencyWindow = opt.Value().(int)
+			}
 			igOptions = append(igOptions, opt)
 		}
 	}
 
 	return &ExponentialPolicy{
-		cOptions:  cOptions,
-		igOptions: igOptions,
+		cOptions:          cOptions,
+		igOptions:         igOptions,
+		latencyFactor:     latencyFactor,
+		latencyPercentile: latencyPercentile,
+		latencyWindow:     latencyWindow,
+		stateless:         newStatelessGenerator(NewExponentialInterval(igOptions...)),
 	}
 }
 
+// IntervalFor returns the interval before the n-th retry.
+// See AttemptIntervalGenerator. Unlike the interval generators, this
+// method is safe to be called concurrently
+func (p *ExponentialPolicy) IntervalFor(n int) time.Duration {
+	return p.stateless.IntervalFor(n)
+}
+
 func (p *ExponentialPolicy) Start(ctx context.Context) Controller {
 	ig := NewExponentialInterval(p.igOptions...)
+	if p.latencyFactor > 0 {
+		return newController(ctx, newLatencyInterval(ig, p.latencyFactor, p.latencyPercentile, p.latencyWindow), p.cOptions...)
+	}
 	return newController(ctx, ig, p.cOptions...)
 }
@@ -46,3 +46,23 @@ func TestNewExponentialIntervalWithOnlyJitterOptions(t *testing.T) {
 	generatedRandomJitter := p.jitter.(*randomJitter)
 	assert.Equal(t, newRandomJitter(jitter, generatedRandomJitter.rng), p.jitter)
 }
+
+func TestNewExponentialIntervalWithJitterStrategy(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	p := NewExponentialInterval(
+		WithJitterFactor(1.5),
+		WithJitterStrategy(JitterPositive),
+		WithRNG(rng),
+	)
+	assert.Equal(t, newPositiveJitter(1.5, rng), p.jitter)
+
+	for i := 0; i < 100; i++ {
+		v := p.jitter.apply(float64(time.Second))
+		if !assert.True(t, v >= float64(time.Second) && v <= float64(2500*time.Millisecond), `jittered value (%s) should be in [1s, 2.5s]`, time.Duration(v)) {
+			return
+		}
+	}
+
+	p = NewExponentialInterval(WithJitterFactor(1.5))
+	assert.Equal(t, &nopJitter{}, p.jitter, `factors >= 1.0 should disable symmetric jitter`)
+}
@@ -0,0 +1,179 @@
+package backoff
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Default values taken from gRPC's connection backoff protocol
+// (https://github.com/grpc/grpc/blob/master/doc/connection-backoff.md)
+const (
+	defaultGRPCConnectMaxInterval       = float64(120 * time.Second)
+	defaultGRPCConnectMinInterval       = float64(time.Second)
+	defaultGRPCConnectMultiplier        = 1.6
+	defaultGRPCConnectJitterFactor      = 0.2
+	defaultGRPCConnectMinConnectTimeout = 20 * time.Second
+)
+
+// GRPCConnectInterval is an interval generator that follows gRPC's
+// connection backoff protocol.
+//
+// Unlike ExponentialInterval, the un-jittered base interval is kept
+// separately from the jittered intervals that are returned, so that
+// jitter does not compound across attempts. Also as per the protocol,
+// the first interval is never jittered.
+type GRPCConnectInterval struct {
+	base        float64
+	maxInterval float64
+	minInterval float64
+	multiplier  float64
+	jitter      jitter
+}
+
+// NewGRPCConnectInterval creates a new GRPCConnectInterval. It accepts
+// the same options as NewExponentialInterval, but the defaults are those
+// specified by gRPC: 1 second initial interval (WithMinInterval),
+// multiplier of 1.6, jitter factor of 0.2, and 120 seconds maximum
+// interval.
+func NewGRPCConnectInterval(options ...ExponentialOption) *GRPCConnectInterval {
+	jitterFactor := defaultGRPCConnectJitterFactor
+	maxInterval := defaultGRPCConnectMaxInterval
+	minInterval := defaultGRPCConnectMinInterval
+	multiplier := defaultGRPCConnectMultiplier
+	var rng Random
+	var jitterStrategy JitterStrategy
+
+	for _, option := range options {
+		switch option.Ident() {
+		case identJitterFactor{}:
+			jitterFactor = option.Value().(float64)
+		case identJitterStrategy{}:
+			jitterStrategy = option.Value().(JitterStrategy)
+		case identMaxInterval{}:
+			maxInterval = float64(option.Value().(time.Duration))
+		case identMinInterval{}:
+			minInterval = float64(option.Value().(time.Duration))
+		case identMultiplier{}:
+			multiplier = option.Value().(float64)
+		case identRNG{}:
+			rng = option.Value().(Random)
+		}
+	}
+
+	if minInterval > maxInterval {
+		minInterval = maxInterval
+	}
+	if multiplier <= 1 {
+		multiplier = defaultGRPCConnectMultiplier
+	}
+
+	return &GRPCConnectInterval{
+		maxInterval: maxInterval,
+		minInterval: minInterval,
+		multiplier:  multiplier,
+		jitter:      newJitter(jitterFactor, rng, jitterStrategy),
+	}
+}
+
+func (g *GRPCConnectInterval) Next() time.Duration {
+	if g.base == 0 {
+		g.base = g.minInterval
+		return time.Duration(g.base)
+	}
+
+	g.base *= g.multiplier
+	if g.base > g.maxInterval {
+		g.base = g.maxInterval
+	}
+
+	// Jitter is applied to the returned value only, never to the base
+	return time.Duration(g.jitter.apply(g.base))
+}
+
+func (g *GRPCConnectInterval) String() string {
+	return fmt.Sprintf("grpc-connect(min=%s, max=%s, multiplier=%g)", time.Duration(g.minInterval), time.Duration(g.maxInterval), g.multiplier)
+}
+
+// IntervalFor returns the interval before the n-th retry. As with Next,
+// the first interval is not jittered.
+func (g *GRPCConnectInterval) IntervalFor(n int) time.Duration {
+	if n <= 1 {
+		return time.Duration(g.minInterval)
+	}
+	base := math.Min(g.minInterval*math.Pow(g.multiplier, float64(n-1)), g.maxInterval)
+	return time.Duration(g.jitter.apply(base))
+}
+
+// GRPCConnectPolicy is a policy that follows gRPC's connection backoff
+// protocol. In addition to the intervals between attempts, it computes
+// the timeout that should be used for each connection attempt.
+type GRPCConnectPolicy struct {
+	cOptions          []ControllerOption
+	igOptions         []ExponentialOption
+	minConnectTimeout time.Duration
+	params            *GRPCConnectInterval // only its parameters are used, see ConnectTimeout
+	stateless         *statelessGenerator
+}
+
+// NewGRPCConnectPolicy creates a new GRPCConnectPolicy. See
+// NewGRPCConnectInterval for the default values. Unlike other policies,
+// this policy retries forever unless WithMaxRetries is specified.
+func NewGRPCConnectPolicy(options ...ExponentialOption) *GRPCConnectPolicy {
+	cOptions := []ControllerOption{WithMaxRetries(0)}
+	var igOptions []ExponentialOption
+	minConnectTimeout := defaultGRPCConnectMinConnectTimeout
+
+	for _, option := range options {
+		switch opt := option.(type) {
+		case ControllerOption:
+			cOptions = append(cOptions, opt)
+		default:
+			if opt.Ident() == (identMinConnectTimeout{}) {
+				minConnectTimeout = opt.Value().(time.Duration)
+				continue
+			}
+			igOptions = append(igOptions, opt)
+		}
+	}
+
+	params := NewGRPCConnectInterval(igOptions...)
+	return &GRPCConnectPolicy{
+		cOptions:          cOptions,
+		igOptions:         igOptions,
+		minConnectTimeout: minConnectTimeout,
+		params:            params,
+		stateless:         newStatelessGenerator(params),
+	}
+}
+
+// IntervalFor returns the interval before the n-th retry.
+// See AttemptIntervalGenerator. Unlike the interval generators, this
+// method is safe to be called concurrently
+func (p *GRPCConnectPolicy) IntervalFor(n int) time.Duration {
+	return p.stateless.IntervalFor(n)
+}
+
+func (p *GRPCConnectPolicy) Start(ctx context.Context) Controller {
+	ig := NewGRPCConnectInterval(p.igOptions...)
+	return newController(ctx, ig, p.cOptions...)
+}
+
+// ConnectTimeout returns the timeout for the n-th (1-based) connection
+// attempt. As per the protocol, this is the larger of the minimum
+// connect timeout (see WithMinConnectTimeout) and the interval until
+// the next attempt. The un-jittered interval is used, so the returned
+// value is deterministic.
+func (p *GRPCConnectPolicy) ConnectTimeout(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+
+	g := p.params
+	base := math.Min(g.minInterval*math.Pow(g.multiplier, float64(n-1)), g.maxInterval)
+	if d := time.Duration(base); d > p.minConnectTimeout {
+		return d
+	}
+	return p.minConnectTimeout
+}
@@ -0,0 +1,74 @@
+package backoff
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGRPCConnectInterval(t *testing.T) {
+	t.Run("No jitter", func(t *testing.T) {
+		ig := NewGRPCConnectInterval(WithJitterFactor(0))
+		expected := []time.Duration{
+			time.Second,
+			1600 * time.Millisecond,
+			2560 * time.Millisecond,
+			4096 * time.Millisecond,
+		}
+		for i, d := range expected {
+			if !assert.Equal(t, d, ig.Next(), `interval for iteration %d`, i) {
+				return
+			}
+		}
+	})
+	t.Run("Jitter does not compound", func(t *testing.T) {
+		ig := NewGRPCConnectInterval(WithRNG(rand.New(rand.NewSource(1))))
+		if !assert.Equal(t, time.Second, ig.Next(), `first interval is not jittered`) {
+			return
+		}
+
+		base := float64(time.Second)
+		for i := 0; i < 20; i++ {
+			base *= defaultGRPCConnectMultiplier
+			if base > defaultGRPCConnectMaxInterval {
+				base = defaultGRPCConnectMaxInterval
+			}
+
+			d := float64(ig.Next())
+			if !assert.True(t, d >= base*0.8 && d <= base*1.2+1, `interval for iteration %d (%s) should be within 20%% of %s`, i, time.Duration(d), time.Duration(base)) {
+				return
+			}
+			if !assert.Equal(t, base, ig.base, `base interval should not be jittered`) {
+				return
+			}
+		}
+	})
+}
+
+func TestGRPCConnectPolicy(t *testing.T) {
+	p := NewGRPCConnectPolicy()
+	testcases := []struct {
+		Attempt  int
+		Expected time.Duration
+	}{
+		{Attempt: 1, Expected: 20 * time.Second},
+		{Attempt: 7, Expected: 20 * time.Second},
+		{Attempt: 8, Expected: time.Duration(float64(time.Second) * 26.8435456)},
+		{Attempt: 100, Expected: 120 * time.Second},
+	}
+	for _, tc := range testcases {
+		if !assert.Equal(t, tc.Expected, p.ConnectTimeout(tc.Attempt), `connect timeout for attempt %d`, tc.Attempt) {
+			return
+		}
+	}
+
+	p = NewGRPCConnectPolicy(WithMinConnectTimeout(time.Second), WithMaxRetries(3))
+	if !assert.Equal(t, 1600*time.Millisecond, p.ConnectTimeout(2), `connect timeout should follow the interval`) {
+		return
+	}
+	if !assert.Equal(t, []ControllerOption{WithMaxRetries(0), WithMaxRetries(3)}, p.cOptions, `controller options should be passed`) {
+		return
+	}
+}
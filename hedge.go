@@ -0,0 +1,108 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+)
+
+var errNoEvents = errors.New(`backoff: policy did not fire any events`)
+
+// Hedge calls fn, and if it has not completed by the time the policy
+// fires its next event, calls it again in parallel (a "hedged" request),
+// up to `maxParallel` calls at a time. The result of the first call
+// that succeeds is returned, and the context passed to the other calls
+// is canceled.
+//
+// The first call is made immediately, and the delays before the following
+// calls are taken from the policy, so the number of calls is limited
+// by the policy as well. For example, with
+//
+//	backoff.Constant(backoff.WithInterval(50*time.Millisecond), backoff.WithMaxRetries(2))
+//
+// a second call is made if the first one has not succeeded within 50ms,
+// and a third one 50ms after that. Failed calls do not trigger new calls
+// by themselves: the next call is still made when the policy fires.
+//
+// If all calls fail, the errors are combined using errors.Join. If ctx
+// is canceled first, ctx.Err() is returned. fn must return promptly when
+// its context is canceled; Hedge does not wait for the canceled calls
+// to return.
+func Hedge[T any](ctx context.Context, policy Policy, maxParallel int, fn func(context.Context) (T, error)) (T, error) {
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+
+	hctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+
+	// Each call sends exactly one result. Calls that complete after
+	// Hedge has returned must not block, hence the select on hctx
+	results := make(chan result)
+	call := func() {
+		v, err := fn(hctx)
+		select {
+		case results <- result{value: v, err: err}:
+		case <-hctx.Done():
+		}
+	}
+
+	var zero T
+	var errs []error
+	var inFlight int
+	c := policy.Start(hctx)
+	done := c.Done()
+	for {
+		// Only wait for the next event if another call can be made
+		var next <-chan struct{}
+		if done != nil && inFlight < maxParallel {
+			next = c.Next()
+		}
+
+		// once the controller is done, no more calls will be made
+		if done == nil && inFlight == 0 {
+			if len(errs) == 0 {
+				// the controller stopped without firing any events
+				if err := ctx.Err(); err != nil {
+					return zero, err
+				}
+				return zero, errNoEvents
+			}
+			return zero, errors.Join(errs...)
+		}
+
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				return r.value, nil
+			}
+			errs = append(errs, r.err)
+		case _, ok := <-next:
+			if !ok {
+				done = nil
+				continue
+			}
+			inFlight++
+			go call()
+		case <-done:
+			// Stop waiting for events, but do not ignore the one that
+			// may already be pending. See Continue
+			select {
+			case _, ok := <-c.Next():
+				if ok && inFlight < maxParallel {
+					inFlight++
+					go call()
+				}
+			default:
+			}
+			done = nil
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package backoff_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedge(t *testing.T) {
+	policy := func(retries int) backoff.Policy {
+		return backoff.Constant(
+			backoff.WithInterval(20*time.Millisecond),
+			backoff.WithMaxRetries(retries),
+		)
+	}
+
+	t.Run("First call succeeds", func(t *testing.T) {
+		var calls int32
+		v, err := backoff.Hedge(context.Background(), policy(2), 3, func(context.Context) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			return "ok", nil
+		})
+		if !assert.NoError(t, err, `Hedge should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "ok", v, `value should be returned`) {
+			return
+		}
+		if !assert.Equal(t, int32(1), atomic.LoadInt32(&calls), `no hedged calls should be made`) {
+			return
+		}
+	})
+	t.Run("Hedged call wins", func(t *testing.T) {
+		var calls int32
+		canceled := make(chan struct{})
+		v, err := backoff.Hedge(context.Background(), policy(2), 3, func(ctx context.Context) (int, error) {
+			n := atomic.AddInt32(&calls, 1)
+			if n == 1 {
+				// the first call hangs until it is canceled
+				<-ctx.Done()
+				close(canceled)
+				return 0, ctx.Err()
+			}
+			return int(n), nil
+		})
+		if !assert.NoError(t, err, `Hedge should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 2, v, `value from the second call should be returned`) {
+			return
+		}
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Errorf(`losing call should be canceled`)
+		}
+	})
+	t.Run("All calls fail", func(t *testing.T) {
+		var calls int32
+		_, err := backoff.Hedge(context.Background(), policy(2), 3, func(context.Context) (int, error) {
+			n := atomic.AddInt32(&calls, 1)
+			return 0, errors.New("failed #" + string(rune('0'+n)))
+		})
+		if !assert.Error(t, err, `Hedge should fail`) {
+			return
+		}
+		if !assert.Equal(t, int32(3), atomic.LoadInt32(&calls), `all calls allowed by the policy should be made`) {
+			return
+		}
+		for _, msg := range []string{"failed #1", "failed #2", "failed #3"} {
+			if !assert.Contains(t, err.Error(), msg, `errors should be aggregated`) {
+				return
+			}
+		}
+	})
+	t.Run("Max parallel", func(t *testing.T) {
+		var inFlight, maxInFlight int32
+		_, err := backoff.Hedge(context.Background(), backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(5),
+		), 2, func(context.Context) (int, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return 0, errors.New("failed")
+		})
+		if !assert.Error(t, err, `Hedge should fail`) {
+			return
+		}
+		if !assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight), `at most 2 calls should be in flight`) {
+			return
+		}
+	})
+	t.Run("Context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+
+		_, err := backoff.Hedge(ctx, policy(2), 3, func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		if !assert.Equal(t, context.DeadlineExceeded, err, `context error should be returned`) {
+			return
+		}
+	})
+}
@@ -14,10 +14,79 @@ type Controller interface {
 	Next() <-chan struct{}
 }
 
+// FeedbackController is a Controller that can be told about the outcome
+// of each attempt. The controllers created by the policies in this
+// package (and by NewController) implement this interface. Use the
+// Success and Failure functions, which work with any Controller:
+//
+//	c := policy.Start(ctx)
+//	for backoff.Continue(c) {
+//		if err := op(); err != nil {
+//			backoff.Failure(c, err)
+//			continue
+//		}
+//		backoff.Success(c)
+//		break
+//	}
+//
+// The feedback is passed to the IntervalGenerator if it implements
+// FeedbackIntervalGenerator, and the wait for the next event is
+// rescheduled using the interval that it returns afterwards. For other
+// generators, feedback is ignored.
+type FeedbackController interface {
+	Controller
+	// Success reports that the latest attempt has succeeded
+	Success()
+	// Failure reports that the latest attempt has failed with `err`,
+	// which may be nil. The error is passed on to observers that
+	// implement FeedbackObserver
+	Failure(err error)
+}
+
+// Feedback is the outcome of an attempt. See FeedbackIntervalGenerator
+type Feedback struct {
+	// Success is true if the attempt has succeeded
+	Success bool
+	// Err is the error that the attempt has failed with, if any
+	Err error
+	// Elapsed is the time between the event for the attempt becoming
+	// ready and the outcome being reported, which is roughly the
+	// duration of the attempt. The time is taken before the event is
+	// sent, so it never includes the wait before the event
+	Elapsed time.Duration
+}
+
+// FeedbackIntervalGenerator is an IntervalGenerator that adjusts its
+// intervals based on the outcome of the attempts. See FeedbackController.
+//
+// Feedback is called from the goroutine that reports the outcome, while
+// Next is called from the controller goroutine, so implementations must
+// be safe to be used concurrently. After each feedback, Next is called
+// again to reschedule the wait for the next event, so implementations
+// that keep state should take care not to advance it twice.
+type FeedbackIntervalGenerator interface {
+	IntervalGenerator
+	Feedback(Feedback)
+}
+
+// IntervalGenerator generates the intervals between backoff events.
 type IntervalGenerator interface {
+	// Next returns the interval until the next event. Generators that
+	// have a limited number of intervals may return a negative value
+	// to signal that no more events should be fired. See NewController
 	Next() time.Duration
 }
 
+// AttemptIntervalGenerator is implemented by policies and interval
+// generators that can compute the interval before the n-th retry
+// without keeping any state. This is useful for APIs that ask for
+// the delay of a given attempt, instead of iterating over intervals.
+type AttemptIntervalGenerator interface {
+	// IntervalFor returns the interval before the n-th (1-based) retry.
+	// Jitter is applied to each interval independently.
+	IntervalFor(n int) time.Duration
+}
+
 // Policy is an interface for the backoff policies that this package
 // implements. Users must create a controller object from this
 // policy to actually do anything with it
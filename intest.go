@@ -0,0 +1,10 @@
+//go:build go1.21
+
+package backoff
+
+import "testing"
+
+// inTest reports whether the process is a test binary
+func inTest() bool {
+	return testing.Testing()
+}
@@ -0,0 +1,12 @@
+//go:build !go1.21
+
+package backoff
+
+import "flag"
+
+// inTest reports whether the process is a test binary. testing.Testing
+// is not available before Go 1.21, so this relies on the flags that
+// test binaries register
+func inTest() bool {
+	return flag.Lookup("test.v") != nil
+}
@@ -5,11 +5,37 @@ import (
 	"time"
 )
 
+// JitterStrategy specifies how the jitter factor is applied to
+// the intervals. See WithJitterStrategy
+type JitterStrategy int
+
+const (
+	// JitterSymmetric picks a random value in the range
+	// [interval - interval*factor, interval + interval*factor].
+	// This is the default.
+	JitterSymmetric JitterStrategy = iota
+
+	// JitterPositive picks a random value in the range
+	// [interval, interval + interval*factor], which is what
+	// k8s.io/apimachinery's wait.Jitter does. With this strategy the
+	// jitter factor may be greater than or equal to 1.0, and like with
+	// wait.Backoff, ExponentialPolicy grows the next interval from the
+	// interval before jitter was applied
+	JitterPositive
+)
+
 type jitter interface {
 	apply(interval float64) float64
 }
 
-func newJitter(jitterFactor float64, rng Random) jitter {
+func newJitter(jitterFactor float64, rng Random, strategy JitterStrategy) jitter {
+	if strategy == JitterPositive {
+		if jitterFactor <= 0 {
+			return newNopJitter()
+		}
+		return newPositiveJitter(jitterFactor, rng)
+	}
+
 	if jitterFactor <= 0 || jitterFactor >= 1 {
 		return newNopJitter()
 	}
@@ -32,17 +58,20 @@ type randomJitter struct {
 }
 
 func newRandomJitter(jitterFactor float64, rng Random) *randomJitter {
+	return &randomJitter{
+		jitterFactor: jitterFactor,
+		rng:          defaultRNG(rng),
+	}
+}
+
+func defaultRNG(rng Random) Random {
 	if rng == nil {
 		// if we have a jitter factor, and no RNG is provided, create one.
 		// This is definitely not "secure", but well, if you care enough,
 		// you would provide one
 		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
-
-	return &randomJitter{
-		jitterFactor: jitterFactor,
-		rng:          rng,
-	}
+	return rng
 }
 
 func (j *randomJitter) apply(interval float64) float64 {
@@ -57,3 +86,19 @@ func (j *randomJitter) apply(interval float64) float64 {
 	// see also: https://github.com/cenkalti/backoff/blob/c2975ffa541a1caeca5f76c396cb8c3e7b3bb5f8/exponential.go#L154-L157
 	return jitterMin + j.rng.Float64()*(jitterMax-jitterMin+1)
 }
+
+type positiveJitter struct {
+	jitterFactor float64
+	rng          Random
+}
+
+func newPositiveJitter(jitterFactor float64, rng Random) *positiveJitter {
+	return &positiveJitter{
+		jitterFactor: jitterFactor,
+		rng:          defaultRNG(rng),
+	}
+}
+
+func (j *positiveJitter) apply(interval float64) float64 {
+	return interval + j.rng.Float64()*interval*j.jitterFactor
+}
@@ -0,0 +1,98 @@
+package backoff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLatencyPercentile = 0.9
+	defaultLatencyWindow     = 10
+)
+
+// latencyInterval wraps an ExponentialInterval so that the intervals are
+// never shorter than `factor` times the given percentile of the recent
+// attempt durations (up to the maximum interval). The durations are
+// reported via FeedbackController
+type latencyInterval struct {
+	factor      float64
+	ig          *ExponentialInterval
+	last        time.Duration
+	maxInterval float64
+	mu          *sync.Mutex
+	percentile  float64
+	reuse       bool
+	samples     []time.Duration // ring buffer
+	size        int             // number of valid samples
+	write       int             // next index to write
+}
+
+func newLatencyInterval(ig *ExponentialInterval, factor, percentile float64, window int) *latencyInterval {
+	if percentile <= 0 || percentile > 1 {
+		percentile = defaultLatencyPercentile
+	}
+	if window < 1 {
+		window = defaultLatencyWindow
+	}
+	return &latencyInterval{
+		factor:      factor,
+		ig:          ig,
+		maxInterval: ig.maxInterval,
+		mu:          &sync.Mutex{},
+		percentile:  percentile,
+		samples:     make([]time.Duration, window),
+	}
+}
+
+func (g *latencyInterval) Next() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// When rescheduling after a feedback, do not advance the exponential
+	// progression again, only apply the new latency floor
+	if g.reuse {
+		g.reuse = false
+	} else {
+		g.last = g.ig.Next()
+	}
+
+	next := float64(g.last)
+	if g.size > 0 {
+		next = math.Max(next, g.factor*float64(g.latency()))
+		next = math.Min(next, g.maxInterval)
+	}
+	return time.Duration(next)
+}
+
+func (g *latencyInterval) Feedback(f Feedback) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.samples[g.write] = f.Elapsed
+	g.write = (g.write + 1) % len(g.samples)
+	if g.size < len(g.samples) {
+		g.size++
+	}
+	g.reuse = true
+}
+
+// latency returns the percentile of the samples, using the nearest-rank
+// method. It must be called with the lock held
+func (g *latencyInterval) latency() time.Duration {
+	sorted := make([]time.Duration, g.size)
+	copy(sorted, g.samples[:g.size])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(g.percentile*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func (g *latencyInterval) String() string {
+	return fmt.Sprintf("%s, latency(factor=%g, p%g)", g.ig, g.factor, g.percentile*100)
+}
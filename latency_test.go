@@ -0,0 +1,155 @@
+package backoff_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyFactor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := backofftest.NewFakeClock(time.Time{})
+	r := &feedbackWaitRecorder{}
+	p := backoff.Exponential(
+		backoff.WithClock(clock),
+		backoff.WithLatencyFactor(3),
+		backoff.WithMinInterval(time.Second),
+		backoff.WithMaxInterval(time.Minute),
+		backoff.WithMultiplier(2),
+		backoff.WithObserver(r),
+	)
+
+	c := p.Start(ctx)
+	if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+		return
+	}
+
+	// The attempt takes 500ms: the next interval should be 3 * 500ms,
+	// counted from the end of the attempt
+	clock.Advance(500 * time.Millisecond)
+	backoff.Failure(c, nil)
+	if !assert.Eventually(t, func() bool {
+		return r.last() == 1500*time.Millisecond
+	}, time.Second, time.Millisecond, `wait should be derived from the attempt duration`) {
+		return
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case <-c.Next():
+		t.Errorf(`event should not fire after the minimum interval`)
+		return
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(500 * time.Millisecond)
+	if !assert.True(t, backoff.Continue(c), `second attempt should be allowed`) {
+		return
+	}
+
+	// The second attempt is instant: the p90 of the durations is still
+	// 500ms, but the exponential interval (2s) is now longer
+	backoff.Failure(c, nil)
+	if !assert.Eventually(t, func() bool {
+		return r.last() == 2*time.Second
+	}, time.Second, time.Millisecond, `exponential interval should be used when it is longer`) {
+		return
+	}
+}
+
+func TestLatencyFactorMaxInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := backofftest.NewFakeClock(time.Time{})
+	r := &feedbackWaitRecorder{}
+	c := backoff.Exponential(
+		backoff.WithClock(clock),
+		backoff.WithLatencyFactor(10),
+		backoff.WithMinInterval(time.Minute),
+		backoff.WithMaxInterval(2*time.Minute),
+		backoff.WithObserver(r),
+	).Start(ctx)
+	backoff.Continue(c)
+
+	clock.Advance(30 * time.Second)
+	backoff.Failure(c, nil)
+	if !assert.Eventually(t, func() bool {
+		return r.last() == 2*time.Minute
+	}, time.Second, time.Millisecond, `interval should be capped at the maximum interval`) {
+		return
+	}
+}
+
+// elapsedRecorder records the Elapsed field of the feedback
+type elapsedRecorder struct {
+	backoff.NopObserver
+	mu      sync.Mutex
+	elapsed []time.Duration
+	stopped chan struct{}
+}
+
+func (r *elapsedRecorder) OnStop() {
+	close(r.stopped)
+}
+
+func (r *elapsedRecorder) OnFeedback(_ int, f backoff.Feedback) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.elapsed = append(r.elapsed, f.Elapsed)
+}
+
+func TestFeedbackElapsed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The attempts are instant, so the elapsed time must never include
+	// the wait before the event, however fast the feedback is given
+	clock := backofftest.NewFakeClock(time.Time{})
+	r := &elapsedRecorder{stopped: make(chan struct{})}
+	c := backoff.Constant(
+		backoff.WithClock(clock),
+		backoff.WithInterval(time.Second),
+		backoff.WithMaxRetries(0),
+		backoff.WithObserver(r),
+	).Start(ctx)
+
+	const attempts = 100
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+		}
+		if !assert.True(t, backoff.Continue(c), `attempt %d should be allowed`, i+1) {
+			return
+		}
+		backoff.Failure(c, nil)
+	}
+
+	// Notifications may be dropped if the observer falls behind, so
+	// check those that have been delivered
+	cancel()
+	select {
+	case <-r.stopped:
+	case <-time.After(5 * time.Second):
+		t.Errorf(`OnStop was not called`)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !assert.NotEmpty(t, r.elapsed, `feedback should be observed`) {
+		return
+	}
+	for i, d := range r.elapsed {
+		if !assert.Zero(t, d, `elapsed time (feedback %d) should not include the wait`, i+1) {
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package backoff
+
+import (
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+)
+
+// Labels attached to the goroutines of controllers, which are visible in
+// goroutine profiles. See runtime/pprof
+const (
+	// PolicyLabel describes the intervals used by the controller,
+	// in the same format as ControllerInfo.Policy
+	PolicyLabel = "backoff.policy"
+	// NameLabel is the name given via WithName. It is only set
+	// if a name has been given
+	NameLabel = "backoff.name"
+)
+
+var leakHandler atomic.Value // func(ControllerInfo)
+
+var runningControllers int64
+
+// RunningControllers returns the number of controllers whose goroutine
+// is currently running. Comparing it before and after some code has
+// run tells whether all the controllers started by that code have
+// stopped. See also backofftest.VerifyNoLeaks
+func RunningControllers() int {
+	return int(atomic.LoadInt64(&runningControllers))
+}
+
+// SetLeakHandler specifies a function to be called when a controller is
+// garbage collected while its goroutine is still running. This happens
+// when the context passed to Policy.Start is never canceled, and the
+// controller is dropped before it stops by itself (for example when the
+// user breaks out of the loop on success).
+//
+// Because nobody can receive events from such a controller anymore, the
+// controller is stopped after the handler returns, which also releases
+// its goroutine. This is done whether a handler is set or not.
+//
+// Only the Name, Policy, MaxRetries and Started fields of ControllerInfo
+// are populated. Only controllers started after the handler has been
+// set are checked. Specify nil to disable the check, which is the default.
+func SetLeakHandler(fn func(ControllerInfo)) {
+	leakHandler.Store(fn)
+}
+
+func getLeakHandler() func(ControllerInfo) {
+	fn, _ := leakHandler.Load().(func(ControllerInfo))
+	return fn
+}
+
+// controllerHandle is the Controller that is returned to the users.
+// Unlike the controller itself, it is not referenced by the controller
+// goroutine, so it may be garbage collected while the goroutine is still
+// running
+type controllerHandle struct {
+	FeedbackController
+}
+
+// watchLeaks returns a handle for the controller. When the handle is
+// garbage collected, the controller is checked for leaks if a leak
+// handler is set, and then `stop` is called to release its goroutine, or
+// to deliver the final notifications of a controller that has run out
+// of events without the user coming back.
+func watchLeaks(c FeedbackController, stop func(), name, policy string, maxRetries int) Controller {
+	fn := getLeakHandler()
+	info := ControllerInfo{
+		Name:       name,
+		Policy:     policy,
+		MaxRetries: maxRetries,
+		Started:    time.Now(),
+	}
+
+	// The finalizer must not reference the handle itself, otherwise it
+	// would never become unreachable
+	h := &controllerHandle{FeedbackController: c}
+	runtime.SetFinalizer(h, func(*controllerHandle) {
+		// A controller that has fired all of its events is not a leak
+		if fn != nil {
+			select {
+			case <-c.Done():
+			default:
+				fn(info)
+			}
+		}
+		stop()
+	})
+	return h
+}
+
+// runLabeled runs fn in a new goroutine that is labeled with the name
+// and the policy of the controller
+func runLabeled(ctx context.Context, name, policy string, fn func()) {
+	labels := []string{PolicyLabel, policy}
+	if name != "" {
+		labels = append(labels, NameLabel, name)
+	}
+	atomic.AddInt64(&runningControllers, 1)
+	go pprof.Do(ctx, pprof.Labels(labels...), func(context.Context) {
+		defer atomic.AddInt64(&runningControllers, -1)
+		fn()
+	})
+}
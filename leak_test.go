@@ -0,0 +1,120 @@
+package backoff_test
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLeakHandler(t *testing.T) {
+	leaked := make(chan backoff.ControllerInfo, 1)
+	backoff.SetLeakHandler(func(info backoff.ControllerInfo) {
+		if info.Name == "TestSetLeakHandler" {
+			leaked <- info
+		}
+	})
+	defer backoff.SetLeakHandler(nil)
+
+	func() {
+		// The context is never canceled, and the controller is dropped
+		// before it stops by itself
+		c := backoff.Constant(
+			backoff.WithName("TestSetLeakHandler"),
+			backoff.WithInterval(time.Hour),
+		).Start(context.Background())
+		backoff.Continue(c)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case info := <-leaked:
+			if !assert.Equal(t, "constant(interval=1h0m0s)", info.Policy, `policy should match`) {
+				return
+			}
+			return
+		case <-timeout:
+			t.Errorf(`leak was not reported`)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestGoroutineLabels(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backoff.Exponential(backoff.WithName("TestGoroutineLabels")).Start(ctx)
+
+	// the labels are set once the goroutine starts running
+	if !assert.Eventually(t, func() bool {
+		var buf bytes.Buffer
+		if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+			return false
+		}
+		return strings.Contains(buf.String(), `"backoff.name":"TestGoroutineLabels"`)
+	}, time.Second, time.Millisecond, `controller goroutine should be labeled`) {
+		return
+	}
+}
+
+func TestRunningControllers(t *testing.T) {
+	before := backoff.RunningControllers()
+
+	var stopped int64
+	o := &countingObserver{stopped: &stopped}
+	for i := 0; i < 100; i++ {
+		// The context is never canceled, and the user does not come back
+		// after the last event
+		c := backoff.Null(backoff.WithObserver(o)).Start(context.Background())
+		for backoff.Continue(c) {
+			break
+		}
+
+		c = backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(2),
+			backoff.WithObserver(o),
+		).Start(context.Background())
+		var attempts int
+		for backoff.Continue(c) {
+			attempts++
+			if attempts == 3 {
+				break
+			}
+		}
+	}
+
+	if !assert.Eventually(t, func() bool {
+		return backoff.RunningControllers() <= before
+	}, 5*time.Second, time.Millisecond, `controller goroutines should exit after their last event`) {
+		return
+	}
+
+	// OnStop is delivered once the controllers are garbage collected
+	if !assert.Eventually(t, func() bool {
+		runtime.GC()
+		return atomic.LoadInt64(&stopped) == 200
+	}, 5*time.Second, 10*time.Millisecond, `observers should be notified that the controllers stopped`) {
+		return
+	}
+}
+
+type countingObserver struct {
+	backoff.NopObserver
+	stopped *int64
+}
+
+func (o *countingObserver) OnStop() {
+	atomic.AddInt64(o.stopped, 1)
+}
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentTypeText        = `text/plain; version=0.0.4; charset=utf-8`
+	contentTypeOpenMetrics = `application/openmetrics-text; version=1.0.0; charset=utf-8`
+)
+
+// Handler returns an http.Handler that exposes the metrics in the
+// Prometheus text exposition format, or in the OpenMetrics format if
+// the client asks for it via the Accept header.
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openMetrics := acceptsOpenMetrics(r.Header.Get("Accept"))
+		if openMetrics {
+			w.Header().Set("Content-Type", contentTypeOpenMetrics)
+		} else {
+			w.Header().Set("Content-Type", contentTypeText)
+		}
+		_ = c.WriteText(w, openMetrics)
+	})
+}
+
+func acceptsOpenMetrics(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == "application/openmetrics-text" {
+			return true
+		}
+	}
+	return false
+}
+
+type counterFamily struct {
+	name  string
+	help  string
+	value func(Snapshot) uint64
+}
+
+type histogramFamily struct {
+	name  string
+	help  string
+	value func(Snapshot) HistogramSnapshot
+}
+
+var counterFamilies = []counterFamily{
+	{
+		name:  "backoff_controllers_started",
+		help:  "Number of controllers started.",
+		value: func(s Snapshot) uint64 { return s.ControllersStarted },
+	},
+	{
+		name:  "backoff_attempts",
+		help:  "Number of attempts made by the users of controllers.",
+		value: func(s Snapshot) uint64 { return s.Attempts },
+	},
+	{
+		name:  "backoff_successes",
+		help:  "Number of controllers whose users reported a successful attempt.",
+		value: func(s Snapshot) uint64 { return s.Successes },
+	},
+	{
+		name:  "backoff_retries_exhausted",
+		help:  "Number of controllers that refused to retry a failed attempt because they ran out of retries.",
+		value: func(s Snapshot) uint64 { return s.RetriesExhausted },
+	},
+	{
+		name:  "backoff_cancellations",
+		help:  "Number of controllers that were stopped via their context before a successful attempt was reported or retries ran out.",
+		value: func(s Snapshot) uint64 { return s.Cancellations },
+	},
+}
+
+var histogramFamilies = []histogramFamily{
+	{
+		name:  "backoff_wait_seconds",
+		help:  "Intervals that users of controllers waited before retrying.",
+		value: func(s Snapshot) HistogramSnapshot { return s.Wait },
+	},
+	{
+		name:  "backoff_time_to_success_seconds",
+		help:  "Time between the start of a controller and the report of its first successful attempt.",
+		value: func(s Snapshot) HistogramSnapshot { return s.TimeToSuccess },
+	},
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format
+// (version 0.0.4), or in the OpenMetrics text format if `openMetrics`
+// is true.
+func (c *Collector) WriteText(dst io.Writer, openMetrics bool) error {
+	snapshots := c.Snapshot()
+	names := make([]string, 0, len(snapshots))
+	for name := range snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := bufio.NewWriter(dst)
+	for _, f := range counterFamilies {
+		// In OpenMetrics the _total suffix is only used for samples
+		family := f.name + "_total"
+		if openMetrics {
+			family = f.name
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n", family, f.help)
+		fmt.Fprintf(w, "# TYPE %s counter\n", family)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s_total{policy=\"%s\"} %d\n", f.name, escapeLabel(name), f.value(snapshots[name]))
+		}
+	}
+
+	for _, f := range histogramFamilies {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(w, "# TYPE %s histogram\n", f.name)
+		for _, name := range names {
+			label := escapeLabel(name)
+			h := f.value(snapshots[name])
+			for _, b := range h.Buckets {
+				fmt.Fprintf(w, "%s_bucket{policy=\"%s\",le=\"%s\"} %d\n", f.name, label, formatFloat(b.UpperBound), b.Count)
+			}
+			fmt.Fprintf(w, "%s_bucket{policy=\"%s\",le=\"+Inf\"} %d\n", f.name, label, h.Count)
+			fmt.Fprintf(w, "%s_sum{policy=\"%s\"} %s\n", f.name, label, formatFloat(h.Sum))
+			fmt.Fprintf(w, "%s_count{policy=\"%s\"} %d\n", f.name, label, h.Count)
+		}
+	}
+
+	if openMetrics {
+		fmt.Fprintf(w, "# EOF\n")
+	}
+	return w.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
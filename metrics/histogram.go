@@ -0,0 +1,55 @@
+package metrics
+
+// histogram is a cumulative histogram with fixed buckets. It is
+// protected by the Collector's lock
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // counts[i] is the number of values <= buckets[i]
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Bucket is a histogram bucket. Count is the number of observed values
+// that are less than or equal to UpperBound
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// HistogramSnapshot is a point-in-time copy of a histogram. Buckets
+// are cumulative, and do not include the implicit +Inf bucket, whose
+// count is the same as Count.
+type HistogramSnapshot struct {
+	Buckets []Bucket `json:"buckets"`
+	Count   uint64   `json:"count"`
+	Sum     float64  `json:"sum"`
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	buckets := make([]Bucket, len(h.buckets))
+	for i, upper := range h.buckets {
+		buckets[i] = Bucket{UpperBound: upper, Count: h.counts[i]}
+	}
+	return HistogramSnapshot{
+		Buckets: buckets,
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+}
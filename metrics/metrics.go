@@ -0,0 +1,199 @@
+// Package metrics collects statistics about backoff controllers, and
+// exports them via expvar or in the Prometheus/OpenMetrics text
+// exposition format, without depending on the Prometheus client library.
+//
+//	collector := metrics.NewCollector()
+//	expvar.Publish("backoff", collector)
+//	http.Handle("/metrics", collector.Handler())
+//
+//	p := backoff.Exponential(collector.Observe("fetch_user"))
+//
+// Controllers do not know whether an attempt has succeeded unless they
+// are told, so report the outcome of the attempts with backoff.Success
+// and backoff.Failure. Otherwise successful controllers are counted as
+// cancellations when their context is canceled.
+package metrics
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// DefaultBuckets are the upper bounds (in seconds) of the histogram
+// buckets used unless WithBuckets is specified.
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 120, 300}
+
+// Collector keeps track of the events of the controllers created by
+// the policies that it observes, grouped by policy name.
+//
+// Collector implements expvar.Var, so it can be published via
+// expvar.Publish.
+type Collector struct {
+	buckets  []float64
+	mu       *sync.RWMutex
+	policies map[string]*policyMetrics
+}
+
+type policyMetrics struct {
+	started       uint64
+	attempts      uint64
+	successes     uint64
+	exhausted     uint64
+	cancellations uint64
+	wait          *histogram
+	timeToSuccess *histogram
+}
+
+// NewCollector creates a new Collector
+func NewCollector(options ...Option) *Collector {
+	buckets := DefaultBuckets
+	for _, option := range options {
+		switch option.Ident() {
+		case identBuckets{}:
+			buckets = option.Value().([]float64)
+		}
+	}
+
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	return &Collector{
+		buckets:  sorted,
+		mu:       &sync.RWMutex{},
+		policies: make(map[string]*policyMetrics),
+	}
+}
+
+// Observe returns an option that, when passed to a policy constructor,
+// records the events of the controllers created by that policy under
+// the given name. The same name may be used for multiple policies.
+func (c *Collector) Observe(name string) backoff.ControllerOption {
+	return backoff.WithObserverFactory(func() backoff.Observer {
+		c.update(name, func(m *policyMetrics) { m.started++ })
+		return &observer{
+			collector: c,
+			name:      name,
+			start:     time.Now(),
+		}
+	})
+}
+
+// update applies fn to the metrics of the named policy, creating
+// them if necessary
+func (c *Collector) update(name string, fn func(*policyMetrics)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.policies[name]
+	if !ok {
+		m = &policyMetrics{
+			wait:          newHistogram(c.buckets),
+			timeToSuccess: newHistogram(c.buckets),
+		}
+		c.policies[name] = m
+	}
+	fn(m)
+}
+
+// observer is created for each controller. Its methods are called
+// from a single goroutine, so no locking is needed
+type observer struct {
+	collector *Collector
+	gaveUp    bool
+	name      string
+	start     time.Time
+	succeeded bool
+	wait      time.Duration // the wait before the waitFor-th attempt
+	waitFor   int
+}
+
+// OnAttempt records the attempt, and the wait before it. Waits are only
+// recorded once the attempt is made, as a wait may be rescheduled, and
+// the user may stop waiting
+func (o *observer) OnAttempt(n int) {
+	waited := o.waitFor == n
+	o.waitFor = 0
+	o.collector.update(o.name, func(m *policyMetrics) {
+		m.attempts++
+		if waited {
+			m.wait.observe(o.wait.Seconds())
+		}
+	})
+}
+
+func (o *observer) OnWait(n int, d time.Duration) {
+	o.wait, o.waitFor = d, n
+}
+
+// OnFeedback records the first success of the controller, along with the
+// time between the start of the controller and the success
+func (o *observer) OnFeedback(_ int, f backoff.Feedback) {
+	if !f.Success || o.succeeded {
+		return
+	}
+	o.succeeded = true
+	elapsed := time.Since(o.start)
+	o.collector.update(o.name, func(m *policyMetrics) {
+		m.successes++
+		m.timeToSuccess.observe(elapsed.Seconds())
+	})
+}
+
+func (o *observer) OnGiveUp(error) {
+	o.gaveUp = true
+	o.collector.update(o.name, func(m *policyMetrics) { m.exhausted++ })
+}
+
+// OnStop records controllers that were stopped via their context
+// before they succeeded or gave up
+func (o *observer) OnStop() {
+	if o.gaveUp || o.succeeded {
+		return
+	}
+	o.collector.update(o.name, func(m *policyMetrics) { m.cancellations++ })
+}
+
+// Snapshot is a point-in-time copy of the metrics of a policy
+type Snapshot struct {
+	ControllersStarted uint64            `json:"controllers_started"`
+	Attempts           uint64            `json:"attempts"`
+	Successes          uint64            `json:"successes"`
+	RetriesExhausted   uint64            `json:"retries_exhausted"`
+	Cancellations      uint64            `json:"cancellations"`
+	Wait               HistogramSnapshot `json:"wait_seconds"`
+	TimeToSuccess      HistogramSnapshot `json:"time_to_success_seconds"`
+}
+
+// Snapshot returns a copy of the current metrics, keyed by policy name
+func (c *Collector) Snapshot() map[string]Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshots := make(map[string]Snapshot, len(c.policies))
+	for name, m := range c.policies {
+		snapshots[name] = Snapshot{
+			ControllersStarted: m.started,
+			Attempts:           m.attempts,
+			Successes:          m.successes,
+			RetriesExhausted:   m.exhausted,
+			Cancellations:      m.cancellations,
+			Wait:               m.wait.snapshot(),
+			TimeToSuccess:      m.timeToSuccess.snapshot(),
+		}
+	}
+	return snapshots
+}
+
+// String returns the metrics in JSON format. This implements expvar.Var
+func (c *Collector) String() string {
+	buf, err := json.Marshal(c.Snapshot())
+	if err != nil {
+		return `{}`
+	}
+	return string(buf)
+}
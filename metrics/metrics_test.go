@@ -0,0 +1,173 @@
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	collector := metrics.NewCollector(metrics.WithBuckets(0.1, 0.001, 0.01))
+
+	p := backoff.Constant(
+		backoff.WithInterval(5*time.Millisecond),
+		backoff.WithMaxRetries(2),
+		collector.Observe(`fetch "user"`),
+	)
+
+	// one controller that runs out of retries...
+	ctx, cancel := context.WithCancel(context.Background())
+	c := p.Start(ctx)
+	for backoff.Continue(c) {
+	}
+	cancel()
+
+	// ...one that succeeds on the second attempt...
+	ctx, cancel = context.WithCancel(context.Background())
+	c = p.Start(ctx)
+	var count int
+	for backoff.Continue(c) {
+		count++
+		if count == 2 {
+			backoff.Success(c)
+			break
+		}
+		backoff.Failure(c, nil)
+	}
+	cancel()
+
+	// ...one that succeeds on the last attempt...
+	ctx, cancel = context.WithCancel(context.Background())
+	c = p.Start(ctx)
+	count = 0
+	for backoff.Continue(c) {
+		count++
+		if count == 3 {
+			backoff.Success(c)
+			break
+		}
+	}
+	cancel()
+
+	// ...and one that is canceled during the first attempt
+	ctx, cancel = context.WithCancel(context.Background())
+	c = p.Start(ctx)
+	backoff.Continue(c)
+	cancel()
+
+	if !assert.Eventually(t, func() bool {
+		s := collector.Snapshot()[`fetch "user"`]
+		return s.RetriesExhausted == 1 && s.Successes == 2 && s.Cancellations == 1
+	}, 5*time.Second, 10*time.Millisecond, `controllers should be recorded`) {
+		return
+	}
+
+	s := collector.Snapshot()[`fetch "user"`]
+	if !assert.Equal(t, uint64(4), s.ControllersStarted, `controllers started`) {
+		return
+	}
+	if !assert.Equal(t, uint64(9), s.Attempts, `attempts`) {
+		return
+	}
+	if !assert.Equal(t, uint64(5), s.Wait.Count, `waits should be recorded once per retry`) {
+		return
+	}
+	if !assert.Equal(t, uint64(2), s.TimeToSuccess.Count, `time to success should be recorded for each success`) {
+		return
+	}
+	if !assert.Equal(t, []float64{0.001, 0.01, 0.1}, []float64{s.Wait.Buckets[0].UpperBound, s.Wait.Buckets[1].UpperBound, s.Wait.Buckets[2].UpperBound}, `buckets should be sorted`) {
+		return
+	}
+
+	t.Run("expvar", func(t *testing.T) {
+		var v map[string]metrics.Snapshot
+		if !assert.NoError(t, json.Unmarshal([]byte(collector.String()), &v), `String should return JSON`) {
+			return
+		}
+		if !assert.Equal(t, uint64(4), v[`fetch "user"`].ControllersStarted) {
+			return
+		}
+	})
+	t.Run("Prometheus", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		collector.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		if !assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type")) {
+			return
+		}
+		body := rec.Body.String()
+		for _, line := range []string{
+			"# TYPE backoff_controllers_started_total counter",
+			`backoff_controllers_started_total{policy="fetch \"user\""} 4`,
+			`backoff_successes_total{policy="fetch \"user\""} 2`,
+			`backoff_retries_exhausted_total{policy="fetch \"user\""} 1`,
+			`backoff_cancellations_total{policy="fetch \"user\""} 1`,
+			"# TYPE backoff_wait_seconds histogram",
+			`backoff_time_to_success_seconds_count{policy="fetch \"user\""} 2`,
+		} {
+			if !assert.Contains(t, body, line+"\n") {
+				return
+			}
+		}
+		if !assert.Contains(t, body, `backoff_wait_seconds_bucket{policy="fetch \"user\"",le="+Inf"}`) {
+			return
+		}
+		if !assert.NotContains(t, body, "# EOF") {
+			return
+		}
+	})
+	t.Run("OpenMetrics", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0, text/plain;q=0.5")
+		rec := httptest.NewRecorder()
+		collector.Handler().ServeHTTP(rec, req)
+
+		if !assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "application/openmetrics-text")) {
+			return
+		}
+		body := rec.Body.String()
+		if !assert.Contains(t, body, "# TYPE backoff_controllers_started counter\n") {
+			return
+		}
+		if !assert.True(t, strings.HasSuffix(body, "# EOF\n"), `OpenMetrics output should end with # EOF`) {
+			return
+		}
+	})
+}
+
+func TestCollectorNull(t *testing.T) {
+	collector := metrics.NewCollector()
+	p := backoff.Null(collector.Observe("null"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := p.Start(ctx)
+	for backoff.Continue(c) {
+		backoff.Success(c)
+		break
+	}
+
+	if !assert.Eventually(t, func() bool {
+		return collector.Snapshot()["null"].Successes == 1
+	}, 5*time.Second, 10*time.Millisecond, `success should be recorded`) {
+		return
+	}
+	cancel()
+
+	s := collector.Snapshot()["null"]
+	if !assert.Zero(t, s.RetriesExhausted, `a successful run should not be counted as exhausted`) {
+		return
+	}
+	if !assert.Zero(t, s.Wait.Count, `no wait should be recorded`) {
+		return
+	}
+}
@@ -0,0 +1,14 @@
+package metrics
+
+import "github.com/lestrrat-go/option"
+
+type identBuckets struct{}
+
+// Option is an option that can be passed to NewCollector
+type Option = option.Interface
+
+// WithBuckets specifies the upper bounds (in seconds) of the histogram
+// buckets. By default DefaultBuckets is used.
+func WithBuckets(v ...float64) Option {
+	return option.New(identBuckets{}, v)
+}
@@ -7,38 +7,84 @@ import (
 
 // NullPolicy does not do any backoff. It allows the caller
 // to execute the desired code once, and no more
-type NullPolicy struct{}
+type NullPolicy struct {
+	cOptions []ControllerOption
+}
 
-func NewNull() *NullPolicy {
-	return &NullPolicy{}
+// NewNull creates a new NullPolicy. Only ControllerOptions such as
+// WithObserver are accepted. WithMaxRetries is ignored.
+func NewNull(options ...ControllerOption) *NullPolicy {
+	return &NullPolicy{
+		cOptions: options,
+	}
 }
 
 func (p *NullPolicy) Start(ctx context.Context) Controller {
-	return newNullController(ctx)
+	return newNullController(ctx, p.cOptions...)
 }
 
 type nullController struct {
-	mu   *sync.RWMutex
-	ctx  context.Context
-	next chan struct{}
+	*signals
+	budget *RetryBudget
+	mu     *sync.RWMutex
+	ctx    context.Context
+	next   chan struct{}
 }
 
-func newNullController(ctx context.Context) *nullController {
+func newNullController(ctx context.Context, options ...ControllerOption) Controller {
+	var budget *RetryBudget
+	var name string
+	observers := observersFromContext(ctx)
+	for _, option := range options {
+		switch option.Ident() {
+		case identName{}:
+			name = option.Value().(string)
+		case identRetryBudget{}:
+			budget = option.Value().(*RetryBudget)
+		case identObserver{}:
+			observers = append(observers, option.Value().(Observer))
+		case identObserverFactory{}:
+			observers = append(observers, option.Value().(func() Observer)())
+		}
+	}
+
+	if tc := track(name, "null", -1); tc != nil {
+		observers = append(observers, tc)
+	}
+
 	cctx, cancel := context.WithCancel(ctx)
 	c := &nullController{
-		mu:   &sync.RWMutex{},
-		ctx:  cctx,
-		next: make(chan struct{}), // NO BUFFER
+		signals: newSignals(newObserverQueue(observers)),
+		budget:  budget,
+		mu:      &sync.RWMutex{},
+		ctx:     cctx,
+		next:    make(chan struct{}), // NO BUFFER
 	}
-	go func(ch chan struct{}, cancel func()) {
-		select {
-		case <-c.ctx.Done():
-		case ch <- struct{}{}:
+	ch := c.next
+	runLabeled(ctx, name, "null", func() {
+		var n int
+		var reason error
+		defer func() {
+			close(ch)
+			cancel()
+			c.exit(ctx, n, reason, false, false)
+		}()
+
+		for {
+			select {
+			case <-cctx.Done():
+				return
+			case <-c.feedback:
+				// no attempt has been made yet
+			case ch <- struct{}{}:
+				c.observers.attempt(1)
+				// The user gives up only by coming back for another event
+				n, reason = 1, ErrMaxRetries
+				return
+			}
 		}
-		close(ch)
-		cancel()
-	}(c.next, cancel)
-	return c
+	})
+	return watchLeaks(c, func() { cancel(); c.release() }, name, "null", -1)
 }
 
 func (c *nullController) Done() <-chan struct{} {
@@ -48,7 +94,23 @@ func (c *nullController) Done() <-chan struct{} {
 }
 
 func (c *nullController) Next() <-chan struct{} {
+	c.signalAsk()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.next
 }
+
+// Success reports the outcome to the observers, and deposits into the
+// retry budget if any
+func (c *nullController) Success() {
+	if c.budget != nil {
+		c.budget.Deposit()
+	}
+	c.report(Feedback{Success: true})
+}
+
+// Failure reports the outcome to the observers. The null controller
+// never retries, so the outcome does not change anything else
+func (c *nullController) Failure(err error) {
+	c.report(Feedback{Success: false, Err: err})
+}
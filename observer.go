@@ -0,0 +1,200 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrMaxRetries is passed to Observer.OnGiveUp when the controller
+// has fired all of the retries allowed by WithMaxRetries.
+var ErrMaxRetries = errors.New(`backoff: maximum number of retries reached`)
+
+// ErrIntervalsExhausted is passed to Observer.OnGiveUp when the
+// IntervalGenerator has signaled that no more events should be fired.
+var ErrIntervalsExhausted = errors.New(`backoff: no more intervals`)
+
+// Observer receives notifications about the events in a Controller.
+// See WithObserver.
+type Observer interface {
+	// OnAttempt is called when the user receives the n-th (1-based)
+	// event from the controller, and makes the n-th attempt.
+	OnAttempt(n int)
+	// OnWait is called when the user is waiting for `d` before the n-th
+	// event, that is when the user asks for another event after the
+	// (n-1)-th attempt, or reports its failure. It is not called if the
+	// (n-1)-th attempt succeeds and the user does not come back. It may
+	// be called again for the same n if the wait is rescheduled.
+	// See FeedbackController
+	OnWait(n int, d time.Duration)
+	// OnGiveUp is called when the user asks for another event, or reports
+	// the failure of the last attempt, but the controller refuses because
+	// of its own limits, such as ErrMaxRetries. It is not called if the
+	// last attempt succeeds, or if the controller stops because its
+	// context has been canceled.
+	OnGiveUp(reason error)
+	// OnStop is called when the controller stops, for whatever reason.
+	// It is always the last method to be called. If the controller runs
+	// out of events and the user never comes back after the last attempt,
+	// it is called once the context passed to Policy.Start is done, or
+	// once the controller has been garbage collected.
+	OnStop()
+}
+
+// NopObserver is an Observer that does nothing. It can be embedded in
+// other types to implement only part of the Observer interface.
+type NopObserver struct{}
+
+func (NopObserver) OnAttempt(int)             {}
+func (NopObserver) OnWait(int, time.Duration) {}
+func (NopObserver) OnGiveUp(error)            {}
+func (NopObserver) OnStop()                   {}
+
+// FeedbackObserver is an Observer that is also notified of the outcome
+// of the n-th attempt, as reported via FeedbackController. Observers
+// given to WithObserver may optionally implement this interface.
+type FeedbackObserver interface {
+	Observer
+	OnFeedback(n int, f Feedback)
+}
+
+type observerContextKey struct{}
+
+// ContextWithObserver returns a context that carries the given Observer.
+// Controllers started with this context (or any context derived from
+// it) notify the observer, in addition to those specified via
+// WithObserver. This allows code that wraps Policy.Start to observe
+// controllers without having access to the options of the policy.
+func ContextWithObserver(ctx context.Context, o Observer) context.Context {
+	prev := observersFromContext(ctx)
+	observers := make([]Observer, len(prev), len(prev)+1)
+	copy(observers, prev)
+	return context.WithValue(ctx, observerContextKey{}, append(observers, o))
+}
+
+func observersFromContext(ctx context.Context) []Observer {
+	observers, _ := ctx.Value(observerContextKey{}).([]Observer)
+	return observers
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) OnAttempt(n int) {
+	for _, o := range m {
+		o.OnAttempt(n)
+	}
+}
+
+func (m multiObserver) OnWait(n int, d time.Duration) {
+	for _, o := range m {
+		o.OnWait(n, d)
+	}
+}
+
+func (m multiObserver) OnFeedback(n int, f Feedback) {
+	for _, o := range m {
+		if fo, ok := o.(FeedbackObserver); ok {
+			fo.OnFeedback(n, f)
+		}
+	}
+}
+
+func (m multiObserver) OnGiveUp(reason error) {
+	for _, o := range m {
+		o.OnGiveUp(reason)
+	}
+}
+
+func (m multiObserver) OnStop() {
+	for _, o := range m {
+		o.OnStop()
+	}
+}
+
+// observerQueueSize is the number of notifications that may be pending
+// before new notifications are dropped.
+const observerQueueSize = 64
+
+// observerQueue delivers notifications to observers from a separate
+// goroutine, so that slow observers never block the controller.
+//
+// If an observer falls behind by more than observerQueueSize
+// notifications, OnAttempt and OnWait notifications are dropped.
+// OnGiveUp and OnStop are always delivered.
+type observerQueue struct {
+	ch     chan func(Observer)
+	once   sync.Once
+	reason error
+}
+
+// newObserverQueue returns nil if there are no observers. All methods
+// of observerQueue may be called on a nil queue.
+func newObserverQueue(observers []Observer) *observerQueue {
+	var o Observer
+	switch len(observers) {
+	case 0:
+		return nil
+	case 1:
+		o = observers[0]
+	default:
+		o = multiObserver(observers)
+	}
+
+	q := &observerQueue{
+		ch: make(chan func(Observer), observerQueueSize),
+	}
+	go q.dispatch(o)
+	return q
+}
+
+func (q *observerQueue) dispatch(o Observer) {
+	for fn := range q.ch {
+		fn(o)
+	}
+
+	// q.reason is set before q.ch is closed
+	if q.reason != nil {
+		o.OnGiveUp(q.reason)
+	}
+	o.OnStop()
+}
+
+func (q *observerQueue) attempt(n int) {
+	q.notify(func(o Observer) { o.OnAttempt(n) })
+}
+
+func (q *observerQueue) wait(n int, d time.Duration) {
+	q.notify(func(o Observer) { o.OnWait(n, d) })
+}
+
+func (q *observerQueue) feedback(n int, f Feedback) {
+	q.notify(func(o Observer) {
+		if fo, ok := o.(FeedbackObserver); ok {
+			fo.OnFeedback(n, f)
+		}
+	})
+}
+
+func (q *observerQueue) notify(fn func(Observer)) {
+	if q == nil {
+		return
+	}
+
+	select {
+	case q.ch <- fn:
+	default:
+	}
+}
+
+// stop delivers OnGiveUp (if reason is not nil) and OnStop. No other
+// methods may be called after stop
+func (q *observerQueue) stop(reason error) {
+	if q == nil {
+		return
+	}
+	q.once.Do(func() {
+		q.reason = reason
+		close(q.ch)
+	})
+}
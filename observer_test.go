@@ -0,0 +1,336 @@
+package backoff_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	attempts []int
+	waits    []int
+	reason   error
+	stopped  chan struct{}
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{stopped: make(chan struct{})}
+}
+
+func (o *recordingObserver) OnAttempt(n int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.attempts = append(o.attempts, n)
+}
+
+func (o *recordingObserver) OnWait(n int, _ time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.waits = append(o.waits, n)
+}
+
+func (o *recordingObserver) OnGiveUp(reason error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.reason = reason
+}
+
+func (o *recordingObserver) OnStop() {
+	close(o.stopped)
+}
+
+func (o *recordingObserver) wait(t *testing.T) bool {
+	t.Helper()
+	select {
+	case <-o.stopped:
+		return true
+	case <-time.After(5 * time.Second):
+		t.Errorf("OnStop was not called")
+		return false
+	}
+}
+
+func TestObserver(t *testing.T) {
+	t.Run("Max retries", func(t *testing.T) {
+		o := newRecordingObserver()
+		p := backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(3),
+			backoff.WithObserver(o),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+		}
+		if !o.wait(t) {
+			return
+		}
+
+		if !assert.Equal(t, []int{1, 2, 3, 4}, o.attempts, `OnAttempt should be called for each attempt`) {
+			return
+		}
+		if !assert.Equal(t, []int{2, 3, 4}, o.waits, `OnWait should be called before each retry`) {
+			return
+		}
+		if !assert.Equal(t, backoff.ErrMaxRetries, o.reason, `OnGiveUp should be called with ErrMaxRetries`) {
+			return
+		}
+	})
+	t.Run("Success on the first attempt", func(t *testing.T) {
+		for _, p := range []backoff.Policy{backoff.Null(), backoff.Exponential(), backoff.Constant()} {
+			o := newRecordingObserver()
+			ctx, cancel := context.WithCancel(backoff.ContextWithObserver(context.Background(), o))
+
+			c := p.Start(ctx)
+			if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+				cancel()
+				return
+			}
+			backoff.Success(c)
+			cancel()
+			if !o.wait(t) {
+				return
+			}
+
+			if !assert.Equal(t, []int{1}, o.attempts, `%T: OnAttempt should be called once`, p) {
+				return
+			}
+			if !assert.Empty(t, o.waits, `%T: OnWait should not be called`, p) {
+				return
+			}
+			if !assert.Nil(t, o.reason, `%T: OnGiveUp should not be called`, p) {
+				return
+			}
+		}
+	})
+	t.Run("Success on the last attempt", func(t *testing.T) {
+		o := newRecordingObserver()
+		p := backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(2),
+			backoff.WithObserver(o),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := p.Start(ctx)
+		var n int
+		for backoff.Continue(c) {
+			n++
+			if n == 3 {
+				backoff.Success(c)
+				break
+			}
+			backoff.Failure(c, nil)
+		}
+		if !o.wait(t) {
+			return
+		}
+
+		if !assert.Equal(t, []int{1, 2, 3}, o.attempts, `OnAttempt should be called for each attempt`) {
+			return
+		}
+		if !assert.Equal(t, []int{2, 3}, o.waits, `OnWait should be called before each retry`) {
+			return
+		}
+		if !assert.Nil(t, o.reason, `OnGiveUp should not be called`) {
+			return
+		}
+	})
+	t.Run("Failure on the last attempt", func(t *testing.T) {
+		o := newRecordingObserver()
+		p := backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(1),
+			backoff.WithObserver(o),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+			backoff.Failure(c, nil)
+		}
+		if !o.wait(t) {
+			return
+		}
+
+		if !assert.Equal(t, backoff.ErrMaxRetries, o.reason, `OnGiveUp should be called with ErrMaxRetries`) {
+			return
+		}
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		o := newRecordingObserver()
+		p := backoff.Exponential(
+			backoff.WithMinInterval(time.Minute),
+			backoff.WithObserver(o),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		c := p.Start(ctx)
+		if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+			cancel()
+			return
+		}
+		cancel()
+		if !o.wait(t) {
+			return
+		}
+
+		if !assert.Equal(t, []int{1}, o.attempts, `OnAttempt should be called once`) {
+			return
+		}
+		if !assert.Nil(t, o.reason, `OnGiveUp should not be called`) {
+			return
+		}
+	})
+	t.Run("Null", func(t *testing.T) {
+		o := newRecordingObserver()
+		p := backoff.Null(backoff.WithObserver(o))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+		}
+		if !o.wait(t) {
+			return
+		}
+
+		if !assert.Equal(t, []int{1}, o.attempts, `OnAttempt should be called once`) {
+			return
+		}
+		if !assert.Equal(t, backoff.ErrMaxRetries, o.reason, `OnGiveUp should be called with ErrMaxRetries`) {
+			return
+		}
+	})
+	t.Run("Multiple observers", func(t *testing.T) {
+		o1 := newRecordingObserver()
+		o2 := newRecordingObserver()
+		p := backoff.Null(backoff.WithObserver(o1), backoff.WithObserver(o2))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+		}
+		if !o1.wait(t) || !o2.wait(t) {
+			return
+		}
+	})
+	t.Run("Slow observer does not block", func(t *testing.T) {
+		release := make(chan struct{})
+		o := newRecordingObserver()
+		slow := &blockingObserver{Observer: o, release: release}
+		p := backoff.Constant(
+			backoff.WithInterval(time.Millisecond),
+			backoff.WithMaxRetries(100),
+			backoff.WithObserver(slow),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var count int
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+			count++
+		}
+		if !assert.Equal(t, 101, count, `controller should not be blocked by the observer`) {
+			return
+		}
+
+		close(release)
+		if !o.wait(t) {
+			return
+		}
+		if !assert.Equal(t, backoff.ErrMaxRetries, o.reason, `OnGiveUp should be delivered even if notifications were dropped`) {
+			return
+		}
+	})
+}
+
+// blockingObserver blocks all notifications until released
+type blockingObserver struct {
+	backoff.Observer
+	release chan struct{}
+}
+
+func (o *blockingObserver) OnAttempt(n int) {
+	<-o.release
+	o.Observer.OnAttempt(n)
+}
+
+func TestObserverFactory(t *testing.T) {
+	var mu sync.Mutex
+	var observers []*recordingObserver
+	p := backoff.Constant(
+		backoff.WithInterval(time.Millisecond),
+		backoff.WithMaxRetries(1),
+		backoff.WithObserverFactory(func() backoff.Observer {
+			mu.Lock()
+			defer mu.Unlock()
+			o := newRecordingObserver()
+			observers = append(observers, o)
+			return o
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		c := p.Start(ctx)
+		for backoff.Continue(c) {
+		}
+	}
+
+	if !assert.Len(t, observers, 3, `an observer should be created for each controller`) {
+		return
+	}
+	for _, o := range observers {
+		if !o.wait(t) {
+			return
+		}
+		if !assert.Equal(t, []int{1, 2}, o.attempts, `each observer should only see its own controller`) {
+			return
+		}
+	}
+}
+
+func TestContextWithObserver(t *testing.T) {
+	o1 := newRecordingObserver()
+	o2 := newRecordingObserver()
+	o3 := newRecordingObserver()
+	p := backoff.Null(backoff.WithObserver(o1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx = backoff.ContextWithObserver(ctx, o2)
+	ctx = backoff.ContextWithObserver(ctx, o3)
+	c := p.Start(ctx)
+	for backoff.Continue(c) {
+	}
+
+	for _, o := range []*recordingObserver{o1, o2, o3} {
+		if !o.wait(t) {
+			return
+		}
+		if !assert.Equal(t, []int{1}, o.attempts, `all observers should be notified`) {
+			return
+		}
+	}
+}
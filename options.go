@@ -6,12 +6,28 @@ import (
 	"github.com/lestrrat-go/option"
 )
 
+type identAdditiveStep struct{}
+type identBudgetTTL struct{}
+type identClock struct{}
+type identIdleTimeout struct{}
 type identInterval struct{}
 type identJitterFactor struct{}
+type identJitterStrategy struct{}
+type identLatencyFactor struct{}
+type identLatencyPercentile struct{}
+type identLatencyWindow struct{}
 type identMaxInterval struct{}
+type identMaxKeys struct{}
 type identMaxRetries struct{}
+type identMinConnectTimeout struct{}
 type identMinInterval struct{}
+type identMinRetriesPerSecond struct{}
 type identMultiplier struct{}
+type identName struct{}
+type identObserver struct{}
+type identObserverFactory struct{}
+type identRetryBudget struct{}
+type identRetryPercent struct{}
 type identRNG struct{}
 
 // ControllerOption is an option that may be passed to Policy objects,
@@ -69,6 +85,48 @@ type commonOption struct {
 func (*commonOption) constantOption()    {}
 func (*commonOption) exponentialOption() {}
 
+// RetryBudgetOption is an option that is used by NewRetryBudget.
+type RetryBudgetOption interface {
+	Option
+	retryBudgetOption()
+}
+
+type retryBudgetOption struct {
+	Option
+}
+
+func (*retryBudgetOption) retryBudgetOption() {}
+
+// RegistryOption is an option that is used by NewRegistry.
+type RegistryOption interface {
+	Option
+	registryOption()
+}
+
+type registryOption struct {
+	Option
+}
+
+func (*registryOption) registryOption() {}
+
+// ClockOption is an option that can be passed to the policy constructors,
+// as well as NewRetryBudget and NewRegistry.
+type ClockOption interface {
+	ControllerOption
+	RetryBudgetOption
+	RegistryOption
+}
+
+type clockOption struct {
+	Option
+}
+
+func (*clockOption) constantOption()    {}
+func (*clockOption) controllerOption()  {}
+func (*clockOption) exponentialOption() {}
+func (*clockOption) registryOption()    {}
+func (*clockOption) retryBudgetOption() {}
+
 // WithMaxRetries specifies the maximum number of attempts that can be made
 // by the backoff policies. By default each policy tries up to 10 times.
 //
@@ -80,6 +138,48 @@ func WithMaxRetries(v int) ControllerOption {
 	return &controllerOption{option.New(identMaxRetries{}, v)}
 }
 
+// WithObserver specifies an Observer that is notified of the events
+// in the controllers created by the policy. This option may be
+// specified multiple times to register multiple observers.
+//
+// Observers are notified from a separate goroutine, so a slow observer
+// never delays the controller. If an observer falls too far behind,
+// OnAttempt and OnWait notifications may be dropped, but OnGiveUp and
+// OnStop are always delivered.
+//
+// This option can be passed to all policy constructors, including NullPolicy
+func WithObserver(v Observer) ControllerOption {
+	return &controllerOption{option.New(identObserver{}, v)}
+}
+
+// WithObserverFactory specifies a function that creates a new Observer
+// every time a controller is started. Use this instead of WithObserver
+// when the observer needs to keep state for each controller, such as
+// the time when the controller was started.
+//
+// This option can be passed to all policy constructors, including NullPolicy
+func WithObserverFactory(v func() Observer) ControllerOption {
+	return &controllerOption{option.New(identObserverFactory{}, v)}
+}
+
+// WithClock specifies the Clock used by the controllers created by the
+// policy, by a RetryBudget, or by a Registry. The default is
+// SystemClock(). See Clock
+//
+// This option can be passed to all policy constructors except for
+// NullPolicy, as well as NewRetryBudget and NewRegistry
+func WithClock(v Clock) ClockOption {
+	return &clockOption{option.New(identClock{}, v)}
+}
+
+// WithName specifies the name of the controllers created by the policy.
+// The name is used to identify the controllers in LiveControllers.
+//
+// This option can be passed to all policy constructors, including NullPolicy
+func WithName(v string) ControllerOption {
+	return &controllerOption{option.New(identName{}, v)}
+}
+
 // WithInterval specifies the constant interval used in ConstantPolicy and
 // ConstantInterval.
 // The default value is 1 minute.
@@ -112,16 +212,125 @@ func WithMultiplier(v float64) ExponentialOption {
 // WithJitterFactor enables some randomness (jittering) in the computation of
 // the backoff intervals. This value must be between 0.0 < v < 1.0. If a
 // value outside of this range is specified, the value will be silently
-// ignored and jittering is disabled.
+// ignored and jittering is disabled. (With JitterPositive, any value
+// greater than 0.0 is accepted. See WithJitterStrategy)
 //
 // This option can be passed to ExponentialPolicy or ConstantPolicy constructor
 func WithJitterFactor(v float64) CommonOption {
 	return &commonOption{option.New(identJitterFactor{}, v)}
 }
 
+// WithJitterStrategy specifies how the jitter factor given by
+// WithJitterFactor is applied to the intervals. The default is
+// JitterSymmetric.
+//
+// This option can be passed to ExponentialPolicy or ConstantPolicy constructor
+func WithJitterStrategy(v JitterStrategy) CommonOption {
+	return &commonOption{option.New(identJitterStrategy{}, v)}
+}
+
 // WithRNG specifies the random number generator used for jittering.
 // If not provided one will be created, but if you want a truly random
 // jittering, make sure to provide one that you explicitly initialized
 func WithRNG(v Random) CommonOption {
 	return &commonOption{option.New(identRNG{}, v)}
 }
+
+// WithMinConnectTimeout specifies the minimum amount of time that a
+// single connection attempt is allowed to take. The default value is
+// 20 seconds.
+//
+// This option is only meaningful for GRPCConnectPolicy.
+func WithMinConnectTimeout(v time.Duration) ExponentialOption {
+	return &exponentialOption{option.New(identMinConnectTimeout{}, v)}
+}
+
+// WithRetryBudget specifies a RetryBudget that limits the retries of the
+// controllers created by the policy. Each controller deposits into the
+// budget when the user reports a success via backoff.Success, and
+// withdraws from it when the user asks for a retry, so that attempts
+// which succeed, however slowly, do not use up the budget. If the budget
+// has no retries left, the controller stops instead of retrying, and
+// observers receive ErrRetryBudgetExhausted.
+//
+// This option can be passed to all policy constructors. NullPolicy only
+// deposits into the budget, as it never retries.
+func WithRetryBudget(v *RetryBudget) ControllerOption {
+	return &controllerOption{option.New(identRetryBudget{}, v)}
+}
+
+// WithBudgetTTL specifies the amount of time deposits and withdrawals
+// are kept in a RetryBudget. The default value is 10 seconds, and values
+// less than 1 second are rounded up to 1 second.
+func WithBudgetTTL(v time.Duration) RetryBudgetOption {
+	return &retryBudgetOption{option.New(identBudgetTTL{}, v)}
+}
+
+// WithMinRetriesPerSecond specifies the number of retries per second
+// that a RetryBudget allows regardless of the number of requests.
+// The default value is 10.
+func WithMinRetriesPerSecond(v float64) RetryBudgetOption {
+	return &retryBudgetOption{option.New(identMinRetriesPerSecond{}, v)}
+}
+
+// WithRetryPercent specifies the number of retries that each successful
+// request deposits into a RetryBudget. For example 0.2 allows one retry
+// for every five successes, on top of WithMinRetriesPerSecond. The
+// default value is 0.2.
+func WithRetryPercent(v float64) RetryBudgetOption {
+	return &retryBudgetOption{option.New(identRetryPercent{}, v)}
+}
+
+// WithAdditiveStep specifies the amount by which the interval of an
+// AdaptivePolicy is decreased after each success. The default value is
+// the minimum interval.
+//
+// This option is only meaningful for AdaptivePolicy.
+func WithAdditiveStep(v time.Duration) ExponentialOption {
+	return &exponentialOption{option.New(identAdditiveStep{}, v)}
+}
+
+// WithLatencyFactor makes the intervals of ExponentialPolicy depend on
+// how long the attempts take: each interval is at least `v` times the
+// recent attempt duration (see WithLatencyPercentile), but no longer
+// than the maximum interval. This is useful for slow APIs, where a fixed
+// minimum interval is either too short or too long.
+//
+// The duration of each attempt is measured by the controller, from the
+// time the event is fired until the outcome is reported via the Success
+// or Failure methods of FeedbackController. Until the first outcome is
+// reported, the usual exponential intervals are used.
+//
+// This option is only meaningful for ExponentialPolicy. By default it is
+// disabled.
+func WithLatencyFactor(v float64) ExponentialOption {
+	return &exponentialOption{option.New(identLatencyFactor{}, v)}
+}
+
+// WithLatencyPercentile specifies the percentile of the recent attempt
+// durations used by WithLatencyFactor, between 0.0 and 1.0. The default
+// value is 0.9 (p90).
+func WithLatencyPercentile(v float64) ExponentialOption {
+	return &exponentialOption{option.New(identLatencyPercentile{}, v)}
+}
+
+// WithLatencyWindow specifies the number of recent attempt durations
+// kept by each controller for WithLatencyFactor. The default value is 10.
+func WithLatencyWindow(v int) ExponentialOption {
+	return &exponentialOption{option.New(identLatencyWindow{}, v)}
+}
+
+// WithMaxKeys specifies the maximum number of keys kept in a Registry.
+// When there are more keys, the least recently used ones are evicted.
+// The default value is 10000. Specify 0 to disable the limit.
+func WithMaxKeys(v int) RegistryOption {
+	return &registryOption{option.New(identMaxKeys{}, v)}
+}
+
+// WithIdleTimeout specifies the amount of time after which a key that
+// has not failed again is removed from a Registry, provided that it may
+// be contacted again. The default value is 1 hour. Specify 0 to disable
+// the timeout.
+func WithIdleTimeout(v time.Duration) RegistryOption {
+	return &registryOption{option.New(identIdleTimeout{}, v)}
+}
@@ -0,0 +1,219 @@
+package backoff
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRegistryMaxKeys     = 10000
+	defaultRegistryIdleTimeout = time.Hour
+)
+
+// Registry keeps an independent backoff state for each key, such as a
+// host name, that persists across requests. For each key it counts the
+// consecutive failures, and computes when the key may be contacted next
+// using the IntervalFor method of a policy: after the n-th consecutive
+// failure, the key must not be contacted for IntervalFor(n).
+//
+//	r := backoff.NewRegistry(backoff.NewExponentialPolicy())
+//	if !r.Allow(host) {
+//		return // try another host, or come back later
+//	}
+//	if err := fetch(host); err != nil {
+//		r.Failure(host)
+//		return
+//	}
+//	r.Success(host)
+//
+// Keys are created lazily on the first failure, and removed on success.
+// To keep the memory bounded, the least recently used keys are evicted
+// when there are more than WithMaxKeys keys, and keys that have not been
+// used for WithIdleTimeout are removed once they may be contacted again.
+// An evicted key starts over as if it had never failed.
+//
+// Registry is safe to be used concurrently.
+type Registry struct {
+	clock       Clock
+	entries     map[string]*list.Element
+	idleTimeout time.Duration
+	lru         *list.List // front is the most recently used
+	maxKeys     int
+	mu          *sync.Mutex
+	policy      AttemptIntervalGenerator
+}
+
+type registryEntry struct {
+	failures int
+	key      string
+	lastUsed time.Time
+	next     time.Time
+}
+
+// NewRegistry creates a new Registry. Any of the policies in this package
+// except for NullPolicy can be used as the policy.
+func NewRegistry(policy AttemptIntervalGenerator, options ...RegistryOption) *Registry {
+	clock := SystemClock()
+	idleTimeout := defaultRegistryIdleTimeout
+	maxKeys := defaultRegistryMaxKeys
+
+	for _, option := range options {
+		switch option.Ident() {
+		case identClock{}:
+			clock = option.Value().(Clock)
+		case identIdleTimeout{}:
+			idleTimeout = option.Value().(time.Duration)
+		case identMaxKeys{}:
+			maxKeys = option.Value().(int)
+		}
+	}
+
+	return &Registry{
+		clock:       clock,
+		entries:     make(map[string]*list.Element),
+		idleTimeout: idleTimeout,
+		lru:         list.New(),
+		maxKeys:     maxKeys,
+		mu:          &sync.Mutex{},
+		policy:      policy,
+	}
+}
+
+// Failure records a failure for the key, and returns the time when the
+// key may be contacted next.
+func (r *Registry) Failure(key string) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	r.expire(now)
+
+	var e *registryEntry
+	if elem, ok := r.entries[key]; ok {
+		e = elem.Value.(*registryEntry)
+		r.lru.MoveToFront(elem)
+	} else {
+		e = &registryEntry{key: key}
+		r.entries[key] = r.lru.PushFront(e)
+		r.evict()
+	}
+
+	e.failures++
+	e.lastUsed = now
+	e.next = now.Add(r.policy.IntervalFor(e.failures))
+	return e.next
+}
+
+// Success resets the state of the key.
+func (r *Registry) Success(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[key]; ok {
+		r.remove(elem)
+	}
+}
+
+// NextAttempt returns the time when the key may be contacted next. If it
+// may be contacted right away, the zero value is returned.
+func (r *Registry) NextAttempt(key string) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	elem, ok := r.entries[key]
+	if !ok {
+		return time.Time{}
+	}
+
+	e := elem.Value.(*registryEntry)
+	if !e.next.After(now) {
+		return time.Time{}
+	}
+	return e.next
+}
+
+// Allow reports whether the key may be contacted right away.
+func (r *Registry) Allow(key string) bool {
+	return r.NextAttempt(key).IsZero()
+}
+
+// Wait blocks until the key may be contacted, or until the context is
+// done, in which case the error from the context is returned.
+func (r *Registry) Wait(ctx context.Context, key string) error {
+	for {
+		next := r.NextAttempt(key)
+		if next.IsZero() {
+			return nil
+		}
+
+		t := r.clock.NewTimer(next.Sub(r.clock.Now()))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C():
+			// another failure may have been recorded in the meantime,
+			// so check again
+		}
+	}
+}
+
+// Failures returns the number of consecutive failures of the key.
+func (r *Registry) Failures(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[key]; ok {
+		return elem.Value.(*registryEntry).failures
+	}
+	return 0
+}
+
+// Len returns the number of keys in the registry.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lru.Len()
+}
+
+// expire removes the idle keys, starting from the least recently used
+// one. It must be called with the lock held
+func (r *Registry) expire(now time.Time) {
+	if r.idleTimeout <= 0 {
+		return
+	}
+
+	for elem := r.lru.Back(); elem != nil; {
+		e := elem.Value.(*registryEntry)
+		if now.Sub(e.lastUsed) < r.idleTimeout {
+			// the rest of the keys have been used more recently
+			return
+		}
+
+		prev := elem.Prev()
+		if !e.next.After(now) {
+			r.remove(elem)
+		}
+		elem = prev
+	}
+}
+
+// evict removes the least recently used keys while there are too many.
+// It must be called with the lock held
+func (r *Registry) evict() {
+	if r.maxKeys <= 0 {
+		return
+	}
+	for r.lru.Len() > r.maxKeys {
+		r.remove(r.lru.Back())
+	}
+}
+
+// remove must be called with the lock held
+func (r *Registry) remove(elem *list.Element) {
+	r.lru.Remove(elem)
+	delete(r.entries, elem.Value.(*registryEntry).key)
+}
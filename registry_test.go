@@ -0,0 +1,166 @@
+package backoff_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRegistry(clock backoff.Clock, options ...backoff.RegistryOption) *backoff.Registry {
+	policy := backoff.NewExponentialPolicy(
+		backoff.WithMinInterval(time.Second),
+		backoff.WithMaxInterval(time.Minute),
+		backoff.WithMultiplier(2),
+	)
+	return backoff.NewRegistry(policy, append([]backoff.RegistryOption{backoff.WithClock(clock)}, options...)...)
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Failures", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		r := newTestRegistry(clock)
+
+		if !assert.True(t, r.Allow(`a`), `unknown keys should be allowed`) {
+			return
+		}
+		if !assert.True(t, r.NextAttempt(`a`).IsZero(), `unknown keys have no next attempt`) {
+			return
+		}
+
+		for i, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+			next := r.Failure(`a`)
+			if !assert.Equal(t, clock.Now().Add(expected), next, `failure #%d`, i+1) {
+				return
+			}
+			if !assert.Equal(t, next, r.NextAttempt(`a`), `NextAttempt should match`) {
+				return
+			}
+			if !assert.False(t, r.Allow(`a`), `key should be backing off`) {
+				return
+			}
+			if !assert.True(t, r.Allow(`b`), `other keys should not be affected`) {
+				return
+			}
+			clock.Advance(expected)
+			if !assert.True(t, r.Allow(`a`), `key should be allowed after the interval`) {
+				return
+			}
+		}
+		if !assert.Equal(t, 3, r.Failures(`a`), `failures should be counted`) {
+			return
+		}
+
+		r.Success(`a`)
+		if !assert.Equal(t, 0, r.Failures(`a`), `success should reset the failures`) {
+			return
+		}
+		if !assert.Equal(t, 0, r.Len(), `success should remove the key`) {
+			return
+		}
+		if !assert.Equal(t, clock.Now().Add(time.Second), r.Failure(`a`), `backoff should start over`) {
+			return
+		}
+	})
+	t.Run("MaxKeys", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		r := newTestRegistry(clock, backoff.WithMaxKeys(2))
+
+		r.Failure(`a`)
+		r.Failure(`b`)
+		r.Failure(`a`) // a is now more recently used than b
+		r.Failure(`c`)
+
+		if !assert.Equal(t, 2, r.Len(), `number of keys should be bounded`) {
+			return
+		}
+		if !assert.Equal(t, 0, r.Failures(`b`), `least recently used key should be evicted`) {
+			return
+		}
+		if !assert.Equal(t, 2, r.Failures(`a`), `a should be kept`) {
+			return
+		}
+		if !assert.Equal(t, 1, r.Failures(`c`), `c should be kept`) {
+			return
+		}
+	})
+	t.Run("IdleTimeout", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		policy := backoff.NewConstantPolicy(backoff.WithInterval(2 * time.Minute))
+		r := backoff.NewRegistry(policy,
+			backoff.WithClock(clock),
+			backoff.WithIdleTimeout(time.Minute),
+		)
+
+		r.Failure(`a`)
+		clock.Advance(30 * time.Second)
+		r.Failure(`b`)
+
+		clock.Advance(time.Minute)
+		r.Failure(`c`)
+		if !assert.Equal(t, 3, r.Len(), `keys still backing off should not expire`) {
+			return
+		}
+
+		clock.Advance(40 * time.Second)
+		r.Failure(`d`)
+		if !assert.Equal(t, 0, r.Failures(`a`), `idle key should expire`) {
+			return
+		}
+		if !assert.Equal(t, 1, r.Failures(`b`), `idle key still backing off should be kept`) {
+			return
+		}
+		if !assert.Equal(t, 3, r.Len(), `b, c and d should be kept`) {
+			return
+		}
+	})
+	t.Run("Wait", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		r := newTestRegistry(clock)
+
+		if !assert.NoError(t, r.Wait(context.Background(), `a`), `unknown keys should not wait`) {
+			return
+		}
+
+		r.Failure(`a`)
+		done := make(chan error, 1)
+		go func() { done <- r.Wait(context.Background(), `a`) }()
+
+		clock.BlockUntil(1)
+		select {
+		case <-done:
+			t.Errorf(`Wait should block while the key is backing off`)
+			return
+		default:
+		}
+
+		clock.Advance(time.Second)
+		select {
+		case err := <-done:
+			if !assert.NoError(t, err, `Wait should succeed`) {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Errorf(`Wait should return after the interval`)
+			return
+		}
+
+		r.Failure(`a`)
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() { done <- r.Wait(ctx, `a`) }()
+		clock.BlockUntil(1)
+		cancel()
+		select {
+		case err := <-done:
+			if !assert.Equal(t, context.Canceled, err, `Wait should return the context error`) {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Errorf(`Wait should return when the context is canceled`)
+			return
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package backoff
+
+import (
+	"sync"
+	"time"
+)
+
+// statelessGenerator allows policies to implement AttemptIntervalGenerator
+// on top of the interval generators that their controllers use.
+//
+// Each controller creates its own interval generator, so the wrapped
+// generator is only used for computations that do not depend on the
+// state of a controller. It is still guarded by a mutex, because the
+// random number generator used for jittering is not safe for concurrent
+// use, and policies may be shared by many goroutines.
+type statelessGenerator struct {
+	mu *sync.Mutex
+	g  AttemptIntervalGenerator
+}
+
+func newStatelessGenerator(g AttemptIntervalGenerator) *statelessGenerator {
+	return &statelessGenerator{
+		mu: &sync.Mutex{},
+		g:  g,
+	}
+}
+
+func (s *statelessGenerator) IntervalFor(n int) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.g.IntervalFor(n)
+}
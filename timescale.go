@@ -0,0 +1,51 @@
+package backoff
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// timeScale holds the bits of a float64. Zero means that the time
+// scale has not been set, which is the same as 1.0
+var timeScale uint64
+
+// SetTimeScale multiplies every interval that the controllers wait for
+// by `v`, process-wide. For example, SetTimeScale(0.001) makes all
+// controllers run 1000 times faster. It returns the previous scale.
+//
+// This is intended for integration tests that can not inject a fake
+// clock into every component that uses this package. Outside of test
+// binaries (see testing.Testing) it does nothing and returns 1, so that
+// production code can not accidentally change the intervals. See also
+// backofftest.ScaleTime, which restores the time scale at the end of
+// the test.
+//
+// Only the actual waits are scaled: the intervals reported to observers
+// (and therefore to loggers, metrics and tracers) are not. A value of 0
+// disables waiting altogether. Negative values reset the scale to 1.
+func SetTimeScale(v float64) float64 {
+	if !inTest() {
+		return 1
+	}
+	if v < 0 {
+		v = 1
+	}
+
+	prev := atomic.SwapUint64(&timeScale, math.Float64bits(v))
+	if prev == 0 {
+		return 1
+	}
+	return math.Float64frombits(prev)
+}
+
+// ScaleDuration applies the time scale set via SetTimeScale to `d`.
+// Packages that wait outside of controllers (for example to honor a
+// Retry-After header) may use this so that their waits are scaled as well.
+func ScaleDuration(d time.Duration) time.Duration {
+	bits := atomic.LoadUint64(&timeScale)
+	if bits == 0 || d <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * math.Float64frombits(bits))
+}
@@ -0,0 +1,77 @@
+package backoff_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type waitRecorder struct {
+	backoff.NopObserver
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (r *waitRecorder) OnWait(_ int, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waits = append(r.waits, d)
+}
+
+func TestSetTimeScale(t *testing.T) {
+	prev := backoff.SetTimeScale(0.001)
+	defer backoff.SetTimeScale(prev)
+
+	if !assert.Equal(t, time.Millisecond, backoff.ScaleDuration(time.Second), `ScaleDuration should apply the scale`) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r := &waitRecorder{}
+	c := backoff.Constant(
+		backoff.WithInterval(time.Second),
+		backoff.WithMaxRetries(3),
+		backoff.WithObserver(r),
+	).Start(ctx)
+
+	start := time.Now()
+	var attempts int
+	for backoff.Continue(c) {
+		attempts++
+	}
+	elapsed := time.Since(start)
+
+	if !assert.Equal(t, 4, attempts, `all attempts should be made`) {
+		return
+	}
+	if !assert.True(t, elapsed < time.Second, `waits should be scaled (took %s)`, elapsed) {
+		return
+	}
+
+	// observers receive the unscaled intervals
+	if !assert.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.waits) == 3
+	}, time.Second, time.Millisecond, `waits should be observed`) {
+		return
+	}
+	for _, d := range r.waits {
+		if !assert.Equal(t, time.Second, d, `observed interval should not be scaled`) {
+			return
+		}
+	}
+
+	if !assert.Equal(t, 0.001, backoff.SetTimeScale(-1), `SetTimeScale should return the previous scale`) {
+		return
+	}
+	if !assert.Equal(t, time.Second, backoff.ScaleDuration(time.Second), `negative scale should reset the scale`) {
+		return
+	}
+}
@@ -0,0 +1,148 @@
+package backoff
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ControllerInfo describes a live controller. See LiveControllers
+type ControllerInfo struct {
+	// ID uniquely identifies the controller within the process
+	ID uint64 `json:"id"`
+	// Name is the name given via WithName, if any
+	Name string `json:"name,omitempty"`
+	// Policy describes the intervals used by the controller
+	Policy string `json:"policy"`
+	// Attempts is the number of events fired so far
+	Attempts int `json:"attempts"`
+	// MaxRetries is the value given via WithMaxRetries. 0 means
+	// that the controller retries forever, and -1 is reported for
+	// NullPolicy, which never retries
+	MaxRetries int `json:"max_retries"`
+	// NextAttempt is the time when the next event is scheduled to
+	// be fired. It is the zero value if no event is scheduled
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+	// Started is the time when the controller was started
+	Started time.Time `json:"started"`
+}
+
+var trackingEnabled int32
+
+var tracked = struct {
+	mu          sync.Mutex
+	controllers map[uint64]*trackedController
+	nextID      uint64
+}{
+	controllers: make(map[uint64]*trackedController),
+}
+
+// TrackControllers enables or disables the tracking of live controllers.
+// When enabled, controllers that are started afterwards are registered
+// until they stop, and can be listed via LiveControllers. Tracking is
+// disabled by default.
+//
+// Importing the backoffdebug package enables tracking, and registers an
+// HTTP handler that lists the live controllers.
+func TrackControllers(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	atomic.StoreInt32(&trackingEnabled, i)
+}
+
+// LiveControllers returns the controllers that are currently running,
+// sorted by the time they were started. Only controllers started while
+// tracking is enabled are reported. See TrackControllers
+func LiveControllers() []ControllerInfo {
+	tracked.mu.Lock()
+	list := make([]ControllerInfo, 0, len(tracked.controllers))
+	for _, tc := range tracked.controllers {
+		list = append(list, tc.info())
+	}
+	tracked.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Started.Equal(list[j].Started) {
+			return list[i].Started.Before(list[j].Started)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// trackedController is an Observer that keeps the ControllerInfo of a
+// single controller up to date. It removes itself from the list of live
+// controllers when the controller stops
+type trackedController struct {
+	mu        sync.Mutex
+	data      ControllerInfo
+	scheduled *int64 // UnixNano of the time the timer of the controller is set to fire
+}
+
+// track returns nil if tracking is not enabled
+func track(name, policy string, maxRetries int) *trackedController {
+	if atomic.LoadInt32(&trackingEnabled) == 0 {
+		return nil
+	}
+
+	tracked.mu.Lock()
+	defer tracked.mu.Unlock()
+
+	tracked.nextID++
+	tc := &trackedController{
+		data: ControllerInfo{
+			ID:         tracked.nextID,
+			Name:       name,
+			Policy:     policy,
+			MaxRetries: maxRetries,
+			Started:    time.Now(),
+		},
+	}
+	tracked.controllers[tc.data.ID] = tc
+	return tc
+}
+
+func (tc *trackedController) info() ControllerInfo {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.data
+}
+
+func (tc *trackedController) OnAttempt(n int) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.data.Attempts = n
+	tc.data.NextAttempt = time.Time{}
+}
+
+// OnWait reports the time the timer of the controller is set to fire,
+// as the wait is only announced once the user asks for another event
+func (tc *trackedController) OnWait(int, time.Duration) {
+	if tc.scheduled == nil {
+		return
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.data.NextAttempt = time.Unix(0, atomic.LoadInt64(tc.scheduled))
+}
+
+func (tc *trackedController) OnGiveUp(error) {}
+
+func (tc *trackedController) OnStop() {
+	tracked.mu.Lock()
+	defer tracked.mu.Unlock()
+	delete(tracked.controllers, tc.data.ID)
+}
+
+// describe returns a human readable description of the interval
+// generator, for use in ControllerInfo
+func describe(ig IntervalGenerator) string {
+	if s, ok := ig.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", ig)
+}
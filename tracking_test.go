@@ -0,0 +1,140 @@
+package backoff_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/stretchr/testify/assert"
+)
+
+func findController(name string) (backoff.ControllerInfo, bool) {
+	for _, info := range backoff.LiveControllers() {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return backoff.ControllerInfo{}, false
+}
+
+func TestTrackControllers(t *testing.T) {
+	backoff.TrackControllers(true)
+	defer backoff.TrackControllers(false)
+
+	t.Run("Constant", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		p := backoff.Constant(
+			backoff.WithName("TestTrackControllers/Constant"),
+			backoff.WithInterval(time.Hour),
+			backoff.WithMaxRetries(3),
+		)
+		c := p.Start(ctx)
+		if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+			return
+		}
+		backoff.Failure(c, nil)
+
+		var info backoff.ControllerInfo
+		if !assert.Eventually(t, func() bool {
+			var ok bool
+			info, ok = findController("TestTrackControllers/Constant")
+			return ok && !info.NextAttempt.IsZero()
+		}, time.Second, time.Millisecond, `controller should be tracked`) {
+			return
+		}
+
+		if !assert.Equal(t, "constant(interval=1h0m0s)", info.Policy, `policy should be described`) {
+			return
+		}
+		if !assert.Equal(t, 1, info.Attempts, `attempts should match`) {
+			return
+		}
+		if !assert.Equal(t, 3, info.MaxRetries, `max retries should match`) {
+			return
+		}
+		if !assert.WithinDuration(t, time.Now().Add(time.Hour), info.NextAttempt, time.Second, `next attempt should be an hour from now`) {
+			return
+		}
+
+		cancel()
+		if !assert.Eventually(t, func() bool {
+			_, ok := findController("TestTrackControllers/Constant")
+			return !ok
+		}, time.Second, time.Millisecond, `controller should be untracked after stopping`) {
+			return
+		}
+	})
+	t.Run("Null", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		backoff.Null(backoff.WithName("TestTrackControllers/Null")).Start(ctx)
+
+		info, ok := findController("TestTrackControllers/Null")
+		if !assert.True(t, ok, `controller should be tracked`) {
+			return
+		}
+		if !assert.Equal(t, "null", info.Policy, `policy should be described`) {
+			return
+		}
+
+		cancel()
+		if !assert.Eventually(t, func() bool {
+			_, ok := findController("TestTrackControllers/Null")
+			return !ok
+		}, time.Second, time.Millisecond, `controller should be untracked after stopping`) {
+			return
+		}
+	})
+	t.Run("Scheduled time", func(t *testing.T) {
+		prev := backoff.SetTimeScale(0.5)
+		defer backoff.SetTimeScale(prev)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+		clock := backofftest.NewFakeClock(start)
+		c := backoff.Constant(
+			backoff.WithName("TestTrackControllers/Scheduled"),
+			backoff.WithInterval(time.Hour),
+			backoff.WithClock(clock),
+		).Start(ctx)
+		if !assert.True(t, backoff.Continue(c), `first attempt should be allowed`) {
+			return
+		}
+
+		// the wait is announced some time after it has started
+		clock.Advance(10 * time.Minute)
+		backoff.Failure(c, nil)
+
+		var info backoff.ControllerInfo
+		if !assert.Eventually(t, func() bool {
+			var ok bool
+			info, ok = findController("TestTrackControllers/Scheduled")
+			return ok && !info.NextAttempt.IsZero()
+		}, time.Second, time.Millisecond, `next attempt should be reported`) {
+			return
+		}
+		if !assert.True(t, start.Add(30*time.Minute).Equal(info.NextAttempt), `next attempt should be the time the timer fires (got %s)`, info.NextAttempt) {
+			return
+		}
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		backoff.TrackControllers(false)
+		defer backoff.TrackControllers(true)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		backoff.Exponential(backoff.WithName("TestTrackControllers/Disabled")).Start(ctx)
+		_, ok := findController("TestTrackControllers/Disabled")
+		if !assert.False(t, ok, `controller should not be tracked`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package workqueue
+
+import "time"
+
+// waitingItem is an item waiting to be added to the queue
+type waitingItem[T comparable] struct {
+	index   int
+	item    T
+	readyAt time.Time
+}
+
+// waitingHeap implements heap.Interface, with the item that becomes
+// ready first at the top
+type waitingHeap[T comparable] []*waitingItem[T]
+
+func (h waitingHeap[T]) Len() int {
+	return len(h)
+}
+
+func (h waitingHeap[T]) Less(i, j int) bool {
+	return h[i].readyAt.Before(h[j].readyAt)
+}
+
+func (h waitingHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waitingHeap[T]) Push(x interface{}) {
+	v := x.(*waitingItem[T])
+	v.index = len(*h)
+	*h = append(*h, v)
+}
+
+func (h *waitingHeap[T]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	v := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return v
+}
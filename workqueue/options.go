@@ -0,0 +1,34 @@
+package workqueue
+
+import (
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/option"
+)
+
+type identClock struct{}
+type identPolicy struct{}
+
+// Option is an option that can be passed to New
+type Option = option.Interface
+
+// WithClock specifies the clock used to schedule delayed items. The
+// default is backoff.SystemClock()
+func WithClock(v backoff.Clock) Option {
+	return option.New(identClock{}, v)
+}
+
+// WithPolicy specifies the policy that determines how long AddRateLimited
+// delays an item. An item that has been requeued n times is delayed for
+// policy.IntervalFor(n+1). Any of the policies in the backoff package
+// except for NullPolicy can be used, for example:
+//
+//	workqueue.WithPolicy(backoff.NewConstantPolicy(
+//		backoff.WithInterval(time.Second),
+//	))
+//
+// The default is an exponential policy starting at 5 milliseconds,
+// doubling up to 1000 seconds, which is the same as the default per-item
+// rate limiter in k8s.io/client-go/util/workqueue.
+func WithPolicy(v backoff.AttemptIntervalGenerator) Option {
+	return option.New(identPolicy{}, v)
+}
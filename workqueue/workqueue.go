@@ -0,0 +1,330 @@
+// Package workqueue implements a work queue for reconcile loops, much
+// like the rate limited queue in k8s.io/client-go/util/workqueue.
+//
+// Items that are added while they are already in the queue are
+// coalesced, and an item is never handed to more than one worker at a
+// time: if it is added while being processed, it is queued again when
+// the worker calls Done. Items that fail can be added back with
+// AddRateLimited, which delays them according to the number of times
+// they have been requeued, using any of the policies in the backoff
+// package.
+//
+//	q := workqueue.New[string]()
+//	defer q.ShutDown()
+//
+//	for {
+//		key, shutdown := q.Get()
+//		if shutdown {
+//			return
+//		}
+//		if err := reconcile(key); err != nil {
+//			q.AddRateLimited(key)
+//		} else {
+//			q.Forget(key)
+//		}
+//		q.Done(key)
+//	}
+package workqueue
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+)
+
+// Queue is a work queue of comparable items. Queue must be created with
+// New, and ShutDown must be called to release its resources.
+type Queue[T comparable] struct {
+	clock        backoff.Clock
+	cond         *sync.Cond
+	dirty        map[T]struct{} // items that need to be processed
+	failures     map[T]int
+	mu           *sync.Mutex
+	policy       backoff.AttemptIntervalGenerator
+	processing   map[T]struct{}
+	queue        []T
+	shuttingDown bool
+	stop         chan struct{}
+	waiting      map[T]*waitingItem[T]
+	waitingHeap  waitingHeap[T]
+	wake         chan struct{}
+}
+
+// New creates a new Queue, and starts the goroutine that moves delayed
+// items to the queue once they are ready.
+func New[T comparable](options ...Option) *Queue[T] {
+	clock := backoff.SystemClock()
+	var policy backoff.AttemptIntervalGenerator
+	for _, option := range options {
+		switch option.Ident() {
+		case identClock{}:
+			clock = option.Value().(backoff.Clock)
+		case identPolicy{}:
+			policy = option.Value().(backoff.AttemptIntervalGenerator)
+		}
+	}
+
+	if policy == nil {
+		policy = backoff.NewExponentialPolicy(
+			backoff.WithMinInterval(5*time.Millisecond),
+			backoff.WithMaxInterval(1000*time.Second),
+			backoff.WithMultiplier(2),
+		)
+	}
+
+	mu := &sync.Mutex{}
+	q := &Queue[T]{
+		clock:      clock,
+		cond:       sync.NewCond(mu),
+		dirty:      make(map[T]struct{}),
+		failures:   make(map[T]int),
+		mu:         mu,
+		policy:     policy,
+		processing: make(map[T]struct{}),
+		stop:       make(chan struct{}),
+		waiting:    make(map[T]*waitingItem[T]),
+		wake:       make(chan struct{}, 1),
+	}
+	go q.loop()
+	return q
+}
+
+// Add adds the item to the queue. If the item is already in the queue,
+// this is a no-op. Items added after ShutDown are ignored.
+func (q *Queue[T]) Add(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.add(item)
+}
+
+// add must be called with the lock held
+func (q *Queue[T]) add(item T) {
+	if q.shuttingDown {
+		return
+	}
+	if _, ok := q.dirty[item]; ok {
+		return
+	}
+
+	q.dirty[item] = struct{}{}
+	if _, ok := q.processing[item]; ok {
+		// Done will queue the item again
+		return
+	}
+	q.queue = append(q.queue, item)
+	q.cond.Signal()
+}
+
+// AddAfter adds the item to the queue after the given delay. If the item
+// is already waiting to be added, it is added at whichever time comes
+// first.
+func (q *Queue[T]) AddAfter(item T, d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.shuttingDown {
+		return
+	}
+	if d <= 0 {
+		q.add(item)
+		return
+	}
+
+	readyAt := q.clock.Now().Add(d)
+	if w, ok := q.waiting[item]; ok {
+		if !readyAt.Before(w.readyAt) {
+			return
+		}
+		w.readyAt = readyAt
+		heap.Fix(&q.waitingHeap, w.index)
+	} else {
+		w := &waitingItem[T]{item: item, readyAt: readyAt}
+		q.waiting[item] = w
+		heap.Push(&q.waitingHeap, w)
+	}
+
+	if q.waitingHeap[0].item == item {
+		// the item is now the first to become ready
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// AddRateLimited adds the item to the queue after a delay computed from
+// the policy and the number of times the item has been requeued, and
+// increments that number.
+func (q *Queue[T]) AddRateLimited(item T) {
+	q.mu.Lock()
+	q.failures[item]++
+	n := q.failures[item]
+	q.mu.Unlock()
+
+	q.AddAfter(item, q.policy.IntervalFor(n))
+}
+
+// Forget resets the number of times the item has been requeued. It
+// should be called once the item has been processed successfully. Note
+// that Forget does not remove the item from the queue.
+func (q *Queue[T]) Forget(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.failures, item)
+}
+
+// NumRequeues returns the number of times the item has been requeued
+// with AddRateLimited since it was last forgotten.
+func (q *Queue[T]) NumRequeues(item T) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.failures[item]
+}
+
+// Get blocks until an item can be processed, and returns it. The caller
+// must call Done with the item once it has been processed. If the queue
+// has been shut down and there are no more items, shutdown is true.
+func (q *Queue[T]) Get() (item T, shutdown bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.queue) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.queue) == 0 {
+		return item, true
+	}
+
+	item = q.queue[0]
+	var zero T
+	q.queue[0] = zero
+	q.queue = q.queue[1:]
+
+	q.processing[item] = struct{}{}
+	delete(q.dirty, item)
+	return item, false
+}
+
+// Done marks the item as processed. If the item was added again while
+// it was being processed, it is queued again.
+func (q *Queue[T]) Done(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.processing, item)
+	if _, ok := q.dirty[item]; ok {
+		q.queue = append(q.queue, item)
+		q.cond.Signal()
+	} else if len(q.processing) == 0 {
+		// ShutDownWithDrain may be waiting
+		q.cond.Broadcast()
+	}
+}
+
+// Len returns the number of items ready to be processed. Items being
+// processed and items waiting for their delay are not counted.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// ShutDown stops the queue. Items added afterwards are ignored, and
+// items still waiting for their delay are dropped. Get keeps returning
+// the items already in the queue, and then reports shutdown, so that
+// the workers can exit.
+func (q *Queue[T]) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutDown()
+}
+
+// shutDown must be called with the lock held
+func (q *Queue[T]) shutDown() {
+	if q.shuttingDown {
+		return
+	}
+	q.shuttingDown = true
+	q.waiting = nil
+	q.waitingHeap = nil
+	close(q.stop)
+	q.cond.Broadcast()
+}
+
+// ShutDownWithDrain is like ShutDown, but also waits until the items in
+// the queue have been processed, and Done has been called for all of
+// them. If the context is done first, the error from the context is
+// returned.
+func (q *Queue[T]) ShutDownWithDrain(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutDown()
+
+	// sync.Cond can not be used in a select statement, so wake up the
+	// waiter below when the context is done
+	drained := make(chan struct{})
+	defer close(drained)
+	go func() {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.cond.Broadcast()
+			q.mu.Unlock()
+		}
+	}()
+
+	for len(q.queue) > 0 || len(q.processing) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		q.cond.Wait()
+	}
+	return nil
+}
+
+// ShuttingDown reports whether ShutDown has been called.
+func (q *Queue[T]) ShuttingDown() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.shuttingDown
+}
+
+// loop moves the delayed items to the queue once they are ready
+func (q *Queue[T]) loop() {
+	for {
+		var timer backoff.Timer
+		var timerC <-chan time.Time
+
+		q.mu.Lock()
+		now := q.clock.Now()
+		for len(q.waitingHeap) > 0 {
+			w := q.waitingHeap[0]
+			if w.readyAt.After(now) {
+				timer = q.clock.NewTimer(w.readyAt.Sub(now))
+				timerC = timer.C()
+				break
+			}
+			heap.Pop(&q.waitingHeap)
+			delete(q.waiting, w.item)
+			q.add(w.item)
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-timerC:
+		case <-q.wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+	}
+}
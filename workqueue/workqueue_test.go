@@ -0,0 +1,229 @@
+package workqueue_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/backoff/v2"
+	"github.com/lestrrat-go/backoff/v2/backofftest"
+	"github.com/lestrrat-go/backoff/v2/workqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+type getResult struct {
+	item     string
+	shutdown bool
+}
+
+// get calls Get, failing the test if it does not return in time
+func get(t *testing.T, q *workqueue.Queue[string]) (string, bool) {
+	t.Helper()
+	ch := make(chan getResult, 1)
+	go func() {
+		item, shutdown := q.Get()
+		ch <- getResult{item: item, shutdown: shutdown}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.item, r.shutdown
+	case <-time.After(time.Second):
+		t.Fatalf(`Get should return`)
+		return ``, false
+	}
+}
+
+func newTestQueue(clock backoff.Clock) *workqueue.Queue[string] {
+	return workqueue.New[string](
+		workqueue.WithClock(clock),
+		workqueue.WithPolicy(backoff.NewExponentialPolicy(
+			backoff.WithMinInterval(time.Second),
+			backoff.WithMaxInterval(time.Minute),
+			backoff.WithMultiplier(2),
+		)),
+	)
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("Coalesce", func(t *testing.T) {
+		q := newTestQueue(backofftest.NewFakeClock(time.Time{}))
+		defer q.ShutDown()
+
+		q.Add(`a`)
+		q.Add(`b`)
+		q.Add(`a`)
+		if !assert.Equal(t, 2, q.Len(), `duplicate items should be coalesced`) {
+			return
+		}
+
+		item, _ := get(t, q)
+		if !assert.Equal(t, `a`, item, `items should be returned in order`) {
+			return
+		}
+
+		// a is added while being processed, so it is queued on Done
+		q.Add(`a`)
+		q.Add(`a`)
+		if !assert.Equal(t, 1, q.Len(), `item being processed should not be queued`) {
+			return
+		}
+		q.Done(`a`)
+		if !assert.Equal(t, 2, q.Len(), `item should be queued on Done`) {
+			return
+		}
+
+		for _, expected := range []string{`b`, `a`} {
+			item, _ := get(t, q)
+			if !assert.Equal(t, expected, item, `items should be returned in order`) {
+				return
+			}
+			q.Done(item)
+		}
+		if !assert.Equal(t, 0, q.Len(), `queue should be empty`) {
+			return
+		}
+	})
+	t.Run("AddAfter", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		q := newTestQueue(clock)
+		defer q.ShutDown()
+
+		q.AddAfter(`b`, 2*time.Second)
+		q.AddAfter(`a`, time.Second)
+		q.AddAfter(`b`, 3*time.Second) // later than before, so ignored
+
+		clock.BlockUntil(1)
+		if !assert.Equal(t, 0, q.Len(), `items should be delayed`) {
+			return
+		}
+
+		for _, expected := range []string{`a`, `b`} {
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			item, _ := get(t, q)
+			if !assert.Equal(t, expected, item, `items should be added when ready`) {
+				return
+			}
+			q.Done(item)
+		}
+
+		q.AddAfter(`c`, 0)
+		if !assert.Equal(t, 1, q.Len(), `items without delay should be added right away`) {
+			return
+		}
+	})
+	t.Run("AddRateLimited", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		q := newTestQueue(clock)
+		defer q.ShutDown()
+
+		for i, d := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+			q.AddRateLimited(`a`)
+			if !assert.Equal(t, i+1, q.NumRequeues(`a`), `requeues should be counted`) {
+				return
+			}
+
+			clock.BlockUntil(1)
+			clock.Advance(d - time.Millisecond)
+			if !assert.Equal(t, 0, q.Len(), `item should be delayed for %s`, d) {
+				return
+			}
+			clock.Advance(time.Millisecond)
+			item, _ := get(t, q)
+			if !assert.Equal(t, `a`, item, `item should be added after %s`, d) {
+				return
+			}
+			q.Done(item)
+		}
+
+		q.Forget(`a`)
+		if !assert.Equal(t, 0, q.NumRequeues(`a`), `Forget should reset requeues`) {
+			return
+		}
+		q.AddRateLimited(`a`)
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		item, _ := get(t, q)
+		if !assert.Equal(t, `a`, item, `delay should start over after Forget`) {
+			return
+		}
+	})
+	t.Run("ShutDown", func(t *testing.T) {
+		clock := backofftest.NewFakeClock(time.Time{})
+		q := newTestQueue(clock)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var shutdown bool
+		go func() {
+			defer wg.Done()
+			_, shutdown = q.Get()
+		}()
+
+		q.Add(`a`)
+		wg.Wait()
+		if !assert.False(t, shutdown, `Get should return the item`) {
+			return
+		}
+
+		q.Add(`b`)
+		q.AddAfter(`c`, time.Second)
+		q.ShutDown()
+		if !assert.True(t, q.ShuttingDown(), `queue should be shutting down`) {
+			return
+		}
+
+		q.Add(`d`)
+		item, shutdown := get(t, q)
+		if !assert.False(t, shutdown, `items in the queue should still be returned`) {
+			return
+		}
+		if !assert.Equal(t, `b`, item, `items added after ShutDown should be ignored`) {
+			return
+		}
+
+		clock.Advance(time.Second)
+		if _, shutdown := get(t, q); !assert.True(t, shutdown, `Get should report shutdown`) {
+			return
+		}
+	})
+	t.Run("ShutDownWithDrain", func(t *testing.T) {
+		q := newTestQueue(backofftest.NewFakeClock(time.Time{}))
+
+		q.Add(`a`)
+		item, _ := get(t, q)
+
+		done := make(chan error, 1)
+		go func() { done <- q.ShutDownWithDrain(context.Background()) }()
+
+		select {
+		case <-done:
+			t.Errorf(`ShutDownWithDrain should wait for Done`)
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		q.Done(item)
+		select {
+		case err := <-done:
+			if !assert.NoError(t, err, `ShutDownWithDrain should succeed`) {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Errorf(`ShutDownWithDrain should return after Done`)
+			return
+		}
+
+		q = newTestQueue(backofftest.NewFakeClock(time.Time{}))
+		q.Add(`a`)
+		get(t, q)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if !assert.Equal(t, context.DeadlineExceeded, q.ShutDownWithDrain(ctx), `ShutDownWithDrain should return the context error`) {
+			return
+		}
+	})
+}